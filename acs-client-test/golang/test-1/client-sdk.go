@@ -9,6 +9,8 @@ import (
 	"sort"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend/acs"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
 	// Updating the import path to match project structure
 	client "github.com/AcceleratedCloudStorage/acs-sdk-go/client"
 )
@@ -28,20 +30,28 @@ func main() {
 	}
 	defer client.Close()
 
+	// Remove everything the test creates, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
 	// Create a unique bucket for testing
 	bucket := fmt.Sprintf("test-bucket-%d", time.Now().UnixNano())
-	ctx := context.Background()
 
 	// Create bucket
 	fmt.Printf("Creating bucket: %s\n", bucket)
 	err = client.CreateBucket(ctx, bucket)
 	if err != nil {
 		fmt.Printf("Failed to create bucket: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
+	bucketResource := tracker.Track(cleanup.Bucket, bucket, func(ctx context.Context) error {
+		return acs.RemoveBucket(ctx, client, bucket)
+	})
 	defer func() {
 		fmt.Printf("Cleaning up bucket: %s\n", bucket)
-		client.DeleteBucket(ctx, bucket)
+		if err := bucketResource.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
+		}
 	}()
 
 	// Define test object sizes
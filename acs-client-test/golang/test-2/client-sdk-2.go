@@ -9,6 +9,10 @@ import (
 	"sort"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend/acs"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/resources"
 	// Updating the import path to match project structure
 	client "github.com/AcceleratedCloudStorage/acs-sdk-go/client"
 )
@@ -17,15 +21,19 @@ func main() {
 	fmt.Println("ACS Client SDK Benchmark - Test Suite 2")
 	fmt.Println("======================================")
 
+	// Remove everything the tests create, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
 	// Run large object test
-	largeObjectTest()
+	largeObjectTest(ctx, tracker)
 
 	// Run list operations test
-	listOperationsTest()
+	listOperationsTest(ctx, tracker)
 }
 
 // largeObjectTest tests operations with a large 10GB object
-func largeObjectTest() {
+func largeObjectTest(ctx context.Context, tracker *cleanup.Tracker) {
 	fmt.Println("\n===== LARGE OBJECT TEST =====")
 
 	// Initialize client
@@ -34,12 +42,10 @@ func largeObjectTest() {
 	})
 	if err != nil {
 		fmt.Printf("Failed to create client: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	defer cli.Close()
 
-	ctx := context.Background()
-
 	// Create test bucket
 	bucketName := fmt.Sprintf("large-object-test-%d", time.Now().UnixNano())
 	fmt.Printf("\nCreating bucket: %s\n", bucketName)
@@ -49,10 +55,15 @@ func largeObjectTest() {
 		return
 	}
 
+	bucket := tracker.Track(cleanup.Bucket, bucketName, func(ctx context.Context) error {
+		return acs.RemoveBucket(ctx, cli, bucketName)
+	})
 	defer func() {
 		// Clean up bucket at the end
 		fmt.Printf("\nCleaning up bucket: %s\n", bucketName)
-		cli.DeleteBucket(ctx, bucketName)
+		if err := bucket.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
+		}
 	}()
 
 	// Generate 10GB of random data
@@ -69,20 +80,33 @@ func largeObjectTest() {
 
 	// Upload large object
 	fmt.Println("\nUploading large object...")
+	// The SDK sends and returns whole buffers, so progress follows the
+	// network counters.
+	uploadProgress := progress.StartBytes("Large Object Upload", objectSize)
+	uploadProgress.Watch(resources.NetSent())
 	startTime := time.Now()
 	err = cli.PutObject(ctx, bucketName, key, data)
 	uploadLatency := time.Since(startTime)
+	uploadProgress.Observe(uploadLatency, objectSize, err)
+	uploadProgress.Finish()
 	if err != nil {
 		fmt.Printf("Failed to upload object: %v\n", err)
 		return
 	}
+	object := bucket.Track(cleanup.Object, bucketName+"/"+key, func(ctx context.Context) error {
+		return cli.DeleteObject(ctx, bucketName, key)
+	})
 	calculateMetricsForBenchmark([]time.Duration{uploadLatency}, "Large Object Upload", int(objectSize))
 
 	// Read large object
 	fmt.Println("\nReading large object...")
+	downloadProgress := progress.StartBytes("Large Object Download", objectSize)
+	downloadProgress.Watch(resources.NetReceived())
 	startTime = time.Now()
 	retrievedData, err := cli.GetObject(ctx, bucketName, key)
 	downloadLatency := time.Since(startTime)
+	downloadProgress.Observe(downloadLatency, objectSize, err)
+	downloadProgress.Finish()
 	if err != nil {
 		fmt.Printf("Failed to download object: %v\n", err)
 		return
@@ -108,11 +132,12 @@ func largeObjectTest() {
 		fmt.Printf("Failed to delete object: %v\n", err)
 		return
 	}
+	object.Forget()
 	calculateMetricsForBenchmark([]time.Duration{deleteLatency}, "Large Object Deletion", int(objectSize))
 }
 
 // listOperationsTest tests bucket and object listing operations
-func listOperationsTest() {
+func listOperationsTest(ctx context.Context, tracker *cleanup.Tracker) {
 	fmt.Println("\n===== LIST OPERATIONS TEST =====")
 
 	// Initialize client
@@ -121,20 +146,26 @@ func listOperationsTest() {
 	})
 	if err != nil {
 		fmt.Printf("Failed to create client: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	defer cli.Close()
 
-	ctx := context.Background()
-
 	// Part 1: Bucket List Test
 	baseBucketName := fmt.Sprintf("list-test-%d", time.Now().UnixNano())
 	numBuckets := 100
 	var bucketNames []string
+	buckets := make([]*cleanup.Resource, numBuckets)
+	defer func() {
+		// Remove any bucket the timed deletion below did not
+		for _, bucket := range buckets {
+			bucket.Remove(ctx)
+		}
+	}()
 
 	// Create 100 buckets
 	fmt.Printf("\nCreating %d buckets...\n", numBuckets)
 	bucketCreateLatencies := make([]time.Duration, numBuckets)
+	bucketCreateProgress := progress.Start("Bucket Creation", numBuckets)
 
 	for i := 0; i < numBuckets; i++ {
 		bucketName := fmt.Sprintf("%s-%d", baseBucketName, i)
@@ -143,23 +174,30 @@ func listOperationsTest() {
 		startTime := time.Now()
 		err := cli.CreateBucket(ctx, bucketName)
 		bucketCreateLatencies[i] = time.Since(startTime)
+		bucketCreateProgress.Observe(bucketCreateLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to create bucket %s: %v\n", bucketName, err)
 			continue
 		}
+		buckets[i] = tracker.Track(cleanup.Bucket, bucketName, func(ctx context.Context) error {
+			return acs.RemoveBucket(ctx, cli, bucketName)
+		})
 	}
 
+	bucketCreateProgress.Finish()
 	calculateMetricsForBenchmark(bucketCreateLatencies, "Bucket Creation", 0)
 
 	// List all buckets
 	fmt.Printf("\nListing all buckets...\n")
 	listBucketLatencies := make([]time.Duration, 10) // Perform 10 times for reliable metrics
+	listBucketProgress := progress.Start("Bucket Listing", 10)
 
 	for i := 0; i < 10; i++ {
 		startTime := time.Now()
 		_, err := cli.ListBuckets(ctx)
 		listBucketLatencies[i] = time.Since(startTime)
+		listBucketProgress.Observe(listBucketLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to list buckets: %v\n", err)
@@ -167,23 +205,28 @@ func listOperationsTest() {
 		}
 	}
 
+	listBucketProgress.Finish()
 	calculateMetricsForBenchmark(listBucketLatencies, "Bucket Listing", 0)
 
 	// Delete all buckets
 	fmt.Printf("\nDeleting %d buckets...\n", numBuckets)
 	bucketDeleteLatencies := make([]time.Duration, len(bucketNames))
+	bucketDeleteProgress := progress.Start("Bucket Deletion", len(bucketNames))
 
 	for i, bucketName := range bucketNames {
 		startTime := time.Now()
 		err := cli.DeleteBucket(ctx, bucketName)
 		bucketDeleteLatencies[i] = time.Since(startTime)
+		bucketDeleteProgress.Observe(bucketDeleteLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to delete bucket %s: %v\n", bucketName, err)
 			continue
 		}
+		buckets[i].Forget()
 	}
 
+	bucketDeleteProgress.Finish()
 	calculateMetricsForBenchmark(bucketDeleteLatencies, "Bucket Deletion", 0)
 
 	// Part 2: Object List Test
@@ -198,15 +241,21 @@ func listOperationsTest() {
 		return
 	}
 
+	objectBucket := tracker.Track(cleanup.Bucket, objectTestBucket, func(ctx context.Context) error {
+		return acs.RemoveBucket(ctx, cli, objectTestBucket)
+	})
 	defer func() {
 		// Cleanup bucket at the end
 		fmt.Printf("\nCleaning up bucket: %s\n", objectTestBucket)
-		cli.DeleteBucket(ctx, objectTestBucket)
+		if err := objectBucket.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
+		}
 	}()
 
 	// Create 1000 small objects
 	fmt.Printf("\nCreating %d objects of size 1 byte...\n", numObjects)
 	objectCreateLatencies := make([]time.Duration, numObjects)
+	objectCreateProgress := progress.Start("Object Creation", numObjects)
 	data := []byte("0") // 1 byte of data
 
 	for i := 0; i < numObjects; i++ {
@@ -215,6 +264,7 @@ func listOperationsTest() {
 		startTime := time.Now()
 		err := cli.PutObject(ctx, objectTestBucket, key, data)
 		objectCreateLatencies[i] = time.Since(startTime)
+		objectCreateProgress.Observe(objectCreateLatencies[i], 1, err)
 
 		if err != nil {
 			fmt.Printf("Failed to put object: %v\n", err)
@@ -222,16 +272,19 @@ func listOperationsTest() {
 		}
 	}
 
+	objectCreateProgress.Finish()
 	calculateMetricsForBenchmark(objectCreateLatencies, "Object Creation", 1)
 
 	// List all objects
 	fmt.Printf("\nListing all objects...\n")
 	listObjectLatencies := make([]time.Duration, 10) // Perform 10 times
+	listObjectProgress := progress.Start("Object Listing", 10)
 
 	for i := 0; i < 10; i++ {
 		startTime := time.Now()
 		_, err := cli.ListObjects(ctx, objectTestBucket, nil)
 		listObjectLatencies[i] = time.Since(startTime)
+		listObjectProgress.Observe(listObjectLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to list objects: %v\n", err)
@@ -239,11 +292,13 @@ func listOperationsTest() {
 		}
 	}
 
+	listObjectProgress.Finish()
 	calculateMetricsForBenchmark(listObjectLatencies, "Object Listing", 0)
 
 	// Delete all objects
 	fmt.Printf("\nDeleting %d objects...\n", numObjects)
 	objectDeleteLatencies := make([]time.Duration, numObjects)
+	objectDeleteProgress := progress.Start("Object Deletion", numObjects)
 
 	for i := 0; i < numObjects; i++ {
 		key := fmt.Sprintf("small-object-%d", i)
@@ -251,6 +306,7 @@ func listOperationsTest() {
 		startTime := time.Now()
 		err := cli.DeleteObject(ctx, objectTestBucket, key)
 		objectDeleteLatencies[i] = time.Since(startTime)
+		objectDeleteProgress.Observe(objectDeleteLatencies[i], 1, err)
 
 		if err != nil {
 			fmt.Printf("Failed to delete object: %v\n", err)
@@ -258,6 +314,7 @@ func listOperationsTest() {
 		}
 	}
 
+	objectDeleteProgress.Finish()
 	calculateMetricsForBenchmark(objectDeleteLatencies, "Object Deletion", 1)
 }
 
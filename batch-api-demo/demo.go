@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
 	"github.com/AcceleratedCloudStorage/acs-sdk-go/client"
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/openai/openai-go"
@@ -816,7 +817,9 @@ func (bm *BatchManager) GetBatchProgress(ctx context.Context, batchID string) (*
 }
 
 func main() {
-	ctx := context.Background()
+	// Remove the batch bucket however the demo ends, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
 
 	// Verify API keys are present
 	_, hasOpenAI := os.LookupEnv(openaiAPIKeyEnvVar)
@@ -827,7 +830,7 @@ func main() {
 		fmt.Printf("Please set at least one of these environment variables:\n")
 		fmt.Printf("  - %s (for OpenAI)\n", openaiAPIKeyEnvVar)
 		fmt.Printf("  - %s (for Anthropic)\n", anthropicAPIKeyEnvVar)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 
 	// Initialize batch manager
@@ -838,9 +841,16 @@ func main() {
 	})
 	if err != nil {
 		fmt.Printf("Error initializing batch manager: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
-	defer batchManager.Close()
+	bucket := tracker.Track(cleanup.Bucket, batchManager.bucket, func(context.Context) error {
+		return batchManager.Close()
+	})
+	defer func() {
+		if err := bucket.Remove(ctx); err != nil {
+			fmt.Printf("Error cleaning up batch bucket: %v\n", err)
+		}
+	}()
 
 	// Create initial batch items
 	initialBatchItems := []GenericBatchItem{
@@ -874,7 +884,7 @@ func main() {
 	batchKey, err := batchManager.StoreBatchItems(ctx, initialBatchItems)
 	if err != nil {
 		fmt.Printf("Error storing initial batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("Initial batch stored with key: %s\n", batchKey)
 
@@ -897,7 +907,7 @@ func main() {
 	err = batchManager.AddBatchRequests(ctx, batchKey, additionalItems)
 	if err != nil {
 		fmt.Printf("Error adding items to batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("Added additional items to batch\n")
 
@@ -905,7 +915,7 @@ func main() {
 	batches, err := batchManager.ListBatchRequests(ctx, "")
 	if err != nil {
 		fmt.Printf("Error listing batches: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("\nStored batches:\n")
 	for _, batch := range batches {
@@ -916,7 +926,7 @@ func main() {
 	currentBatch, err := batchManager.LoadBatchItems(ctx, batchKey)
 	if err != nil {
 		fmt.Printf("Error loading current batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("\nCurrent batch contents (%d items):\n", len(currentBatch))
 	for _, item := range currentBatch {
@@ -927,7 +937,7 @@ func main() {
 	err = batchManager.RemoveBatchRequests(ctx, batchKey, []string{"request-1"})
 	if err != nil {
 		fmt.Printf("Error removing items from batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("\nRemoved request-1 from batch\n")
 
@@ -935,7 +945,7 @@ func main() {
 	finalBatch, err := batchManager.LoadBatchItems(ctx, batchKey)
 	if err != nil {
 		fmt.Printf("Error loading final batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	fmt.Printf("\nFinal batch contents (%d items):\n", len(finalBatch))
 	for _, item := range finalBatch {
@@ -946,7 +956,7 @@ func main() {
 	responses, err := batchManager.ProcessBatch(ctx, finalBatch)
 	if err != nil {
 		fmt.Printf("Error processing batch: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 
 	// List all available batches
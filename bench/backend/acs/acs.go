@@ -0,0 +1,147 @@
+// Copyright 2025 Accelerated Cloud Storage Corporation. All Rights Reserved.
+
+// Package acs registers the ACS Object Storage backend. It lives in its own
+// package so the rest of the runner builds without the ACS SDK.
+package acs
+
+import (
+	"context"
+	"fmt"
+
+	client "github.com/AcceleratedCloudStorage/acs-sdk-go/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+const defaultRegion = "us-east-1"
+
+func init() {
+	backend.Register("acs", func(ctx context.Context, cfg backend.Config) (backend.Backend, error) {
+		return New(cfg)
+	})
+}
+
+// Backend adapts the ACS Go SDK client to backend.Backend.
+type Backend struct {
+	client *client.ACSClient
+	region string
+}
+
+// New creates an ACS client for the region in cfg.
+func New(cfg backend.Config) (*Backend, error) {
+	region := cfg.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	cli, err := client.NewClient(&client.Session{
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{client: cli, region: region}, nil
+}
+
+// Client returns the underlying SDK client.
+func (b *Backend) Client() *client.ACSClient { return b.client }
+
+func (b *Backend) Name() string { return "acs" }
+
+// Location returns the session region; the SDK picks the endpoint.
+func (b *Backend) Location(bucket string) (region, endpoint string) { return b.region, "" }
+
+func (b *Backend) CreateBucket(ctx context.Context, bucket string) error {
+	return b.client.CreateBucket(ctx, bucket)
+}
+
+func (b *Backend) DeleteBucket(ctx context.Context, bucket string) error {
+	return b.client.DeleteBucket(ctx, bucket)
+}
+
+// RemoveBucket deletes every object before deleting the bucket.
+func (b *Backend) RemoveBucket(ctx context.Context, bucket string) error {
+	return RemoveBucket(ctx, b.client, bucket)
+}
+
+func (b *Backend) ListBuckets(ctx context.Context) ([]string, error) {
+	buckets, err := b.client.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		names = append(names, bucket.Name)
+	}
+	return names, nil
+}
+
+func (b *Backend) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	return b.client.PutObject(ctx, bucket, key, data)
+}
+
+func (b *Backend) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	data, err := b.client.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, notFound(bucket, key, err)
+	}
+	return data, nil
+}
+
+// HeadObject stats an object with the SDK's HeadObject call.
+func (b *Backend) HeadObject(ctx context.Context, bucket, key string) (backend.ObjectInfo, error) {
+	out, err := b.client.HeadObject(ctx, bucket, key)
+	if err != nil {
+		return backend.ObjectInfo{}, notFound(bucket, key, err)
+	}
+	return backend.ObjectInfo{Size: out.ContentLength, ETag: out.ETag, Metadata: out.Metadata}, nil
+}
+
+// HeadBucket checks a bucket with the SDK's HeadBucket call.
+func (b *Backend) HeadBucket(ctx context.Context, bucket string) error {
+	_, err := b.client.HeadBucket(ctx, bucket)
+	return err
+}
+
+// CopyObject copies on the server with the SDK's CopyObject call, which names
+// the source "bucket/key" as S3 does.
+func (b *Backend) CopyObject(ctx context.Context, bucket, src, dst string) error {
+	return b.client.CopyObject(ctx, bucket, bucket+"/"+src, dst)
+}
+
+// notFound wraps an SDK error for a missing object in backend.ErrNotFound.
+// The SDK reports it with the gRPC NotFound code.
+func notFound(bucket, key string, err error) error {
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("object %s/%s: %w: %w", bucket, key, backend.ErrNotFound, err)
+	}
+	return err
+}
+
+func (b *Backend) DeleteObject(ctx context.Context, bucket, key string) error {
+	return b.client.DeleteObject(ctx, bucket, key)
+}
+
+func (b *Backend) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	return b.client.ListObjects(ctx, bucket, &client.ListObjectsOptions{Prefix: prefix})
+}
+
+func (b *Backend) Close() error {
+	return b.client.Close()
+}
+
+// RemoveBucket deletes every object in bucket and then the bucket. The
+// standalone ACS benchmarks use it for cleanup as well.
+func RemoveBucket(ctx context.Context, cli *client.ACSClient, bucket string) error {
+	keys, err := cli.ListObjects(ctx, bucket, &client.ListObjectsOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list objects in %s: %w", bucket, err)
+	}
+	if len(keys) > 0 {
+		if err := cli.DeleteObjects(ctx, bucket, keys); err != nil {
+			return fmt.Errorf("failed to delete objects in %s: %w", bucket, err)
+		}
+	}
+	return cli.DeleteBucket(ctx, bucket)
+}
@@ -0,0 +1,193 @@
+// Package backend defines the object storage operations the benchmark runner
+// exercises, and a registry of adapters for each service under test.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned (wrapped) when an object or bucket does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Backend is the common surface every storage service adapter implements.
+// It mirrors the calls made by the standalone client-sdk programs.
+type Backend interface {
+	// Name returns the registry name of the backend, e.g. "acs" or "s3".
+	Name() string
+	CreateBucket(ctx context.Context, bucket string) error
+	DeleteBucket(ctx context.Context, bucket string) error
+	ListBuckets(ctx context.Context) ([]string, error)
+	PutObject(ctx context.Context, bucket, key string, data []byte) error
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+	// ListObjects returns every key in bucket that starts with prefix.
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+	Close() error
+}
+
+// BucketNamer is implemented by backends that impose naming rules on buckets,
+// such as S3 Express One Zone directory buckets.
+type BucketNamer interface {
+	BucketName(base string) string
+}
+
+// BucketName returns the bucket name b expects for the given base name.
+func BucketName(b Backend, base string) string {
+	if n, ok := b.(BucketNamer); ok {
+		return n.BucketName(base)
+	}
+	return base
+}
+
+// Locator is implemented by backends that can report where their client
+// connects.
+type Locator interface {
+	// Location returns the region in use and the endpoint requests for
+	// bucket are sent to, or the service endpoint if bucket is empty. The
+	// endpoint is empty if it cannot be resolved.
+	Location(bucket string) (region, endpoint string)
+}
+
+// Location returns the region and endpoint b connects to for bucket, or
+// empty strings if b cannot tell.
+func Location(b Backend, bucket string) (region, endpoint string) {
+	if l, ok := b.(Locator); ok {
+		return l.Location(bucket)
+	}
+	return "", ""
+}
+
+// CredentialResolver is implemented by backends whose clients resolve
+// credentials lazily, on the first request.
+type CredentialResolver interface {
+	ResolveCredentials(ctx context.Context) error
+}
+
+// ResolveCredentials makes b resolve its credentials now, and reports false
+// if b resolves them when it is constructed instead.
+func ResolveCredentials(ctx context.Context, b Backend) (bool, error) {
+	if r, ok := b.(CredentialResolver); ok {
+		return true, r.ResolveCredentials(ctx)
+	}
+	return false, nil
+}
+
+// Config holds the connection settings used to open a backend.
+type Config struct {
+	Name     string `json:"name"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Transport applies to the S3-compatible backends only.
+	Transport Transport `json:"transport,omitzero"`
+}
+
+// OpenFunc constructs a backend from its configuration.
+type OpenFunc func(ctx context.Context, cfg Config) (Backend, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]OpenFunc{}
+)
+
+// Register makes a backend available to Open under name.
+func Register(name string, open OpenFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("backend: Register called twice for " + name)
+	}
+	registry[name] = open
+}
+
+// Open constructs the backend named by cfg.Name.
+func Open(ctx context.Context, cfg Config) (Backend, error) {
+	registryMu.Lock()
+	open, ok := registry[cfg.Name]
+	registryMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q (available: %v)", cfg.Name, Names())
+	}
+	return open(ctx, cfg)
+}
+
+// transportBackends are the backends whose clients apply Config.Transport.
+var transportBackends = map[string]bool{}
+
+// AppliesTransport reports whether the backend registered as name applies
+// Config.Transport to its client. The others manage their own connections.
+func AppliesTransport(name string) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return transportBackends[name]
+}
+
+// Names returns the sorted names of all registered backends.
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EmptyBucket deletes every object in bucket.
+func EmptyBucket(ctx context.Context, b Backend, bucket string) error {
+	keys, err := b.ListObjects(ctx, bucket, "")
+	if err != nil {
+		return fmt.Errorf("failed to list objects in %s: %w", bucket, err)
+	}
+	var errs []error
+	for _, key := range keys {
+		if err := b.DeleteObject(ctx, bucket, key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete object %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BucketRemover is implemented by backends that can empty and delete a
+// bucket more completely than EmptyBucket, e.g. including incomplete
+// multipart uploads.
+type BucketRemover interface {
+	RemoveBucket(ctx context.Context, bucket string) error
+}
+
+// RemoveBucket deletes everything in bucket and then the bucket itself.
+func RemoveBucket(ctx context.Context, b Backend, bucket string) error {
+	if r, ok := b.(BucketRemover); ok {
+		return r.RemoveBucket(ctx, bucket)
+	}
+	if err := EmptyBucket(ctx, b, bucket); err != nil {
+		return err
+	}
+	return b.DeleteBucket(ctx, bucket)
+}
+
+// Ownership marker: a bucket tag that identifies buckets created by the
+// benchmarks, so they can be found and swept whatever their name.
+const (
+	OwnerTagKey   = "bench-owner"
+	OwnerTagValue = "Accelerated-Cloud-Storage/Benchmarks"
+)
+
+// Owner is implemented by backends that can mark buckets with the ownership
+// marker and check for it.
+type Owner interface {
+	MarkOwned(ctx context.Context, bucket string) error
+	Owned(ctx context.Context, bucket string) (bool, error)
+}
+
+// MarkOwned marks bucket as created by the benchmarks if b supports it.
+func MarkOwned(ctx context.Context, b Backend, bucket string) error {
+	if o, ok := b.(Owner); ok {
+		return o.MarkOwned(ctx, bucket)
+	}
+	return nil
+}
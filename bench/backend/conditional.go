@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPreconditionFailed is returned (wrapped) when a conditional write is
+// refused because its condition does not hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ConditionalWriter is implemented by backends that can make a write depend
+// on the object's current state, as S3 does with If-None-Match and
+// If-Match. Both calls return the ETag of the object they wrote, and an
+// error wrapping ErrPreconditionFailed if the condition does not hold.
+type ConditionalWriter interface {
+	// PutObjectIfAbsent writes key only if it does not exist yet.
+	PutObjectIfAbsent(ctx context.Context, bucket, key string, data []byte) (etag string, err error)
+	// PutObjectIfMatch overwrites key only if its ETag is still etag.
+	PutObjectIfMatch(ctx context.Context, bucket, key string, data []byte, etag string) (string, error)
+}
+
+// PutObjectIfAbsent writes an object unless one exists at key.
+func PutObjectIfAbsent(ctx context.Context, b Backend, bucket, key string, data []byte) (string, error) {
+	if w, ok := b.(ConditionalWriter); ok {
+		return w.PutObjectIfAbsent(ctx, bucket, key, data)
+	}
+	return "", fmt.Errorf("conditional write on %s: %w", b.Name(), ErrUnsupported)
+}
+
+// PutObjectIfMatch overwrites an object unless it changed since it had etag.
+func PutObjectIfMatch(ctx context.Context, b Backend, bucket, key string, data []byte, etag string) (string, error) {
+	if w, ok := b.(ConditionalWriter); ok {
+		return w.PutObjectIfMatch(ctx, bucket, key, data, etag)
+	}
+	return "", fmt.Errorf("conditional write on %s: %w", b.Name(), ErrUnsupported)
+}
+
+// SupportsConditionalWrites reports whether b can make writes conditional.
+func SupportsConditionalWrites(b Backend) bool {
+	_, ok := b.(ConditionalWriter)
+	return ok
+}
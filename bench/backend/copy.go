@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+// Copy methods, as reported in results.
+const (
+	// CopyServer copies the whole object on the server in one call.
+	CopyServer = "server-side copy"
+	// CopyParts copies the object on the server in parts, as S3's
+	// UploadPartCopy does.
+	CopyParts = "multipart copy"
+	// CopyClient reads the object and writes it back, for backends that
+	// cannot copy on the server.
+	CopyClient = "GET+PUT"
+)
+
+// Copier is implemented by backends that copy objects on the server,
+// without the data passing through the client.
+type Copier interface {
+	CopyObject(ctx context.Context, bucket, src, dst string) error
+}
+
+// PartCopier is implemented by backends that copy large objects on the
+// server in parts of partSize bytes.
+type PartCopier interface {
+	CopyObjectParts(ctx context.Context, bucket, src, dst string, size, partSize int64) error
+}
+
+// CopyMethod returns how b copies an object of size bytes: in parts when it
+// can and size is at least partThreshold, which 0 disables; otherwise whole
+// on the server when it can; and otherwise through the client.
+func CopyMethod(b Backend, size, partThreshold int64) string {
+	if _, ok := b.(PartCopier); ok && partThreshold > 0 && size >= partThreshold {
+		return CopyParts
+	}
+	if _, ok := b.(Copier); ok {
+		return CopyServer
+	}
+	return CopyClient
+}
+
+// CopyObject copies src to dst within bucket using method, as returned by
+// CopyMethod for an object of size bytes.
+func CopyObject(ctx context.Context, b Backend, bucket, src, dst, method string, size, partSize int64) error {
+	switch method {
+	case CopyParts:
+		if c, ok := b.(PartCopier); ok {
+			return c.CopyObjectParts(ctx, bucket, src, dst, size, partSize)
+		}
+	case CopyServer:
+		if c, ok := b.(Copier); ok {
+			return c.CopyObject(ctx, bucket, src, dst)
+		}
+	case CopyClient:
+		data, err := b.GetObject(ctx, bucket, src)
+		if err != nil {
+			return err
+		}
+		return b.PutObject(ctx, bucket, dst, data)
+	}
+	return fmt.Errorf("%s on %s: %w", method, b.Name(), ErrUnsupported)
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error classes reported by ErrorClass.
+const (
+	ClassNotFound     = "not_found"
+	ClassPrecondition = "precondition_failed"
+	ClassThrottled    = "throttled"
+	ClassTimeout      = "timeout"
+	ClassCanceled     = "canceled"
+	ClassClient       = "client_error"
+	ClassServer       = "server_error"
+	ClassNetwork      = "network"
+	ClassOther        = "other"
+)
+
+// throttleCodes are the S3 error codes that mean the request was rate limited.
+var throttleCodes = map[string]bool{
+	"SlowDown":             true,
+	"Throttling":           true,
+	"ThrottlingException":  true,
+	"TooManyRequests":      true,
+	"RequestLimitExceeded": true,
+	"ServiceUnavailable":   true,
+	"RequestThrottled":     true,
+}
+
+// ErrorClass sorts an operation error into a small set of classes suitable
+// for metric labels. It returns "" for a nil error.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrNotFound) {
+		return ClassNotFound
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		return ClassPrecondition
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassCanceled
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && throttleCodes[apiErr.ErrorCode()] {
+		return ClassThrottled
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch status := respErr.HTTPStatusCode(); {
+		case status == 404:
+			return ClassNotFound
+		case status == 412:
+			return ClassPrecondition
+		case status == 429 || status == 503:
+			return ClassThrottled
+		case status >= 500:
+			return ClassServer
+		case status >= 400:
+			return ClassClient
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ClassTimeout
+		}
+		return ClassNetwork
+	}
+	if st, ok := status.FromError(err); ok {
+		if class, ok := grpcClasses[st.Code()]; ok {
+			return class
+		}
+	}
+	return ClassOther
+}
+
+// grpcClasses maps the status codes of gRPC clients, such as the ACS SDK,
+// to error classes.
+var grpcClasses = map[codes.Code]string{
+	codes.NotFound:           ClassNotFound,
+	codes.FailedPrecondition: ClassPrecondition,
+	codes.Aborted:            ClassPrecondition,
+	codes.ResourceExhausted:  ClassThrottled,
+	codes.DeadlineExceeded:   ClassTimeout,
+	codes.Canceled:           ClassCanceled,
+	codes.Unavailable:        ClassNetwork,
+	codes.Internal:           ClassServer,
+	codes.Unknown:            ClassServer,
+}
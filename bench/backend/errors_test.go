@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// responseError returns an S3 client error for an HTTP response of status.
+func responseError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      errors.New("api error"),
+	}}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"not found", fmt.Errorf("object b/k: %w: %w", ErrNotFound, errors.New("NoSuchKey")), ClassNotFound},
+		{"precondition", fmt.Errorf("object b/k: %w", ErrPreconditionFailed), ClassPrecondition},
+		{"deadline", fmt.Errorf("get: %w", context.DeadlineExceeded), ClassTimeout},
+		{"canceled", context.Canceled, ClassCanceled},
+		{"throttle code", &smithy.GenericAPIError{Code: "SlowDown"}, ClassThrottled},
+		{"other api code", &smithy.GenericAPIError{Code: "AccessDenied"}, ClassOther},
+		{"404", responseError(404), ClassNotFound},
+		{"412", responseError(412), ClassPrecondition},
+		{"429", responseError(429), ClassThrottled},
+		{"503", responseError(503), ClassThrottled},
+		{"500", responseError(500), ClassServer},
+		{"403", responseError(403), ClassClient},
+		{"dial", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ClassNetwork},
+		{"dns timeout", &net.DNSError{Name: "example.com", IsTimeout: true}, ClassTimeout},
+		{"grpc unavailable", status.Error(codes.Unavailable, "no connection"), ClassNetwork},
+		{"grpc resource exhausted", status.Error(codes.ResourceExhausted, "quota"), ClassThrottled},
+		{"grpc deadline", status.Error(codes.DeadlineExceeded, "deadline"), ClassTimeout},
+		{"grpc internal", status.Error(codes.Internal, "boom"), ClassServer},
+		{"grpc unknown", status.Error(codes.Unknown, "boom"), ClassServer},
+		{"grpc not found", status.Error(codes.NotFound, "no such key"), ClassNotFound},
+		{"grpc failed precondition", status.Error(codes.FailedPrecondition, "etag"), ClassPrecondition},
+		{"grpc aborted", status.Error(codes.Aborted, "conflict"), ClassPrecondition},
+		{"grpc wrapped", fmt.Errorf("put: %w", status.Error(codes.Unavailable, "")), ClassNetwork},
+		{"grpc unmapped", status.Error(codes.PermissionDenied, "denied"), ClassOther},
+		{"plain", errors.New("something"), ClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorClass(tt.err); got != tt.want {
+				t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package backend
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register("fake", func(ctx context.Context, cfg Config) (Backend, error) {
+		return NewFake(), nil
+	})
+}
+
+// Fake is an in-memory backend for dry runs of workloads without credentials.
+type Fake struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+	owned   map[string]bool
+	// metadata holds the user metadata of objects by bucket and key.
+	metadata map[string]map[string]map[string]string
+}
+
+// NewFake returns an empty in-memory backend.
+func NewFake() *Fake {
+	return &Fake{
+		buckets:  make(map[string]map[string][]byte),
+		owned:    make(map[string]bool),
+		metadata: make(map[string]map[string]map[string]string),
+	}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) CreateBucket(ctx context.Context, bucket string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.buckets[bucket]; ok {
+		return fmt.Errorf("bucket %s already exists", bucket)
+	}
+	f.buckets[bucket] = make(map[string][]byte)
+	return nil
+}
+
+func (f *Fake) DeleteBucket(ctx context.Context, bucket string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		return fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	if len(objects) > 0 {
+		return fmt.Errorf("bucket %s is not empty", bucket)
+	}
+	delete(f.buckets, bucket)
+	delete(f.owned, bucket)
+	delete(f.metadata, bucket)
+	return nil
+}
+
+func (f *Fake) MarkOwned(ctx context.Context, bucket string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.buckets[bucket]; !ok {
+		return fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	f.owned[bucket] = true
+	return nil
+}
+
+func (f *Fake) Owned(ctx context.Context, bucket string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.owned[bucket], nil
+}
+
+func (f *Fake) ListBuckets(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.buckets))
+	for name := range f.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (f *Fake) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.put(bucket, key, data)
+}
+
+// put stores an object; f.mu must be held.
+func (f *Fake) put(bucket, key string, data []byte) error {
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		return fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	objects[key] = append([]byte(nil), data...)
+	delete(f.metadata[bucket], key)
+	return nil
+}
+
+func (f *Fake) PutObjectIfAbsent(ctx context.Context, bucket, key string, data []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.buckets[bucket][key]; ok {
+		return "", fmt.Errorf("object %s/%s exists: %w", bucket, key, ErrPreconditionFailed)
+	}
+	if err := f.put(bucket, key, data); err != nil {
+		return "", err
+	}
+	return etag(data), nil
+}
+
+func (f *Fake) PutObjectIfMatch(ctx context.Context, bucket, key string, data []byte, match string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, ok := f.buckets[bucket][key]
+	if !ok {
+		return "", fmt.Errorf("object %s/%s: %w", bucket, key, ErrNotFound)
+	}
+	if etag(current) != match {
+		return "", fmt.Errorf("object %s/%s changed: %w", bucket, key, ErrPreconditionFailed)
+	}
+	if err := f.put(bucket, key, data); err != nil {
+		return "", err
+	}
+	return etag(data), nil
+}
+
+// etag returns the ETag S3 gives an object uploaded in one part: the quoted
+// hex MD5 of its data.
+func etag(data []byte) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", md5.Sum(data)))
+}
+
+func (f *Fake) PutObjectMetadata(ctx context.Context, bucket, key string, data []byte, metadata map[string]string) error {
+	if err := f.PutObject(ctx, bucket, key, data); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.metadata[bucket] == nil {
+		f.metadata[bucket] = make(map[string]map[string]string)
+	}
+	f.metadata[bucket][key] = maps.Clone(metadata)
+	return nil
+}
+
+func (f *Fake) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.buckets[bucket][key]
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s: %w", bucket, key, ErrNotFound)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (f *Fake) HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.buckets[bucket][key]
+	if !ok {
+		return ObjectInfo{}, fmt.Errorf("object %s/%s: %w", bucket, key, ErrNotFound)
+	}
+	return ObjectInfo{Size: int64(len(data)), ETag: etag(data), Metadata: maps.Clone(f.metadata[bucket][key])}, nil
+}
+
+func (f *Fake) HeadBucket(ctx context.Context, bucket string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.buckets[bucket]; !ok {
+		return fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	return nil
+}
+
+func (f *Fake) CopyObject(ctx context.Context, bucket, src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.buckets[bucket][src]
+	if !ok {
+		return fmt.Errorf("object %s/%s: %w", bucket, src, ErrNotFound)
+	}
+	f.buckets[bucket][dst] = data
+	if md, ok := f.metadata[bucket][src]; ok {
+		f.metadata[bucket][dst] = md
+	} else {
+		delete(f.metadata[bucket], dst)
+	}
+	return nil
+}
+
+// CopyObjectParts assembles dst from partSize byte ranges of src, as a
+// multipart copy does.
+func (f *Fake) CopyObjectParts(ctx context.Context, bucket, src, dst string, size, partSize int64) error {
+	f.mu.Lock()
+	data, ok := f.buckets[bucket][src]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("object %s/%s: %w", bucket, src, ErrNotFound)
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("object %s/%s is %d bytes, not %d", bucket, src, len(data), size)
+	}
+	var out []byte
+	for first := int64(0); first < size; first += partSize {
+		out = append(out, data[first:min(size, first+partSize)]...)
+	}
+	return f.PutObject(ctx, bucket, dst, out)
+}
+
+func (f *Fake) DeleteObject(ctx context.Context, bucket, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		return fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	delete(objects, key)
+	delete(f.metadata[bucket], key)
+	return nil
+}
+
+func (f *Fake) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	var keys []string
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *Fake) Close() error { return nil }
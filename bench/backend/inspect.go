@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned (wrapped) when a backend lacks an optional
+// operation.
+var ErrUnsupported = errors.New("not supported")
+
+// ObjectInfo is what a metadata call reports about an object.
+type ObjectInfo struct {
+	Size int64
+	// ETag identifies the object's current version, quoted as S3 sends it.
+	ETag string
+	// Metadata holds the user metadata stored with the object.
+	Metadata map[string]string
+}
+
+// Inspector is implemented by backends that can stat an object or bucket
+// without reading any data, as HeadObject and HeadBucket do.
+type Inspector interface {
+	HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error)
+	HeadBucket(ctx context.Context, bucket string) error
+}
+
+// HeadObject returns the metadata of an object, or an error wrapping
+// ErrNotFound if it does not exist.
+func HeadObject(ctx context.Context, b Backend, bucket, key string) (ObjectInfo, error) {
+	if i, ok := b.(Inspector); ok {
+		return i.HeadObject(ctx, bucket, key)
+	}
+	return ObjectInfo{}, fmt.Errorf("HeadObject on %s: %w", b.Name(), ErrUnsupported)
+}
+
+// HeadBucket checks that bucket exists and can be accessed.
+func HeadBucket(ctx context.Context, b Backend, bucket string) error {
+	if i, ok := b.(Inspector); ok {
+		return i.HeadBucket(ctx, bucket)
+	}
+	return fmt.Errorf("HeadBucket on %s: %w", b.Name(), ErrUnsupported)
+}
+
+// MetadataWriter is implemented by backends that can store user metadata
+// with an object.
+type MetadataWriter interface {
+	PutObjectMetadata(ctx context.Context, bucket, key string, data []byte, metadata map[string]string) error
+}
+
+// PutObjectMetadata writes an object with user metadata. Without metadata it
+// is a plain PutObject.
+func PutObjectMetadata(ctx context.Context, b Backend, bucket, key string, data []byte, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return b.PutObject(ctx, bucket, key, data)
+	}
+	if w, ok := b.(MetadataWriter); ok {
+		return w.PutObjectMetadata(ctx, bucket, key, data, metadata)
+	}
+	return fmt.Errorf("user metadata on %s: %w", b.Name(), ErrUnsupported)
+}
+
+// SupportsMetadata reports whether b can store user metadata.
+func SupportsMetadata(b Backend) bool {
+	_, ok := b.(MetadataWriter)
+	return ok
+}
@@ -0,0 +1,434 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+)
+
+// partCopyConcurrency is how many parts CopyObjectParts copies at once, as
+// many as the SDK's upload manager uploads by default.
+const partCopyConcurrency = 5
+
+const (
+	defaultS3Region   = "us-east-1"
+	tigrisEndpoint    = "https://fly.storage.tigris.dev"
+	expressZoneID     = "use1-az6" // Zone ID for us-east-1c
+	expressBucketTail = "--x-s3"
+)
+
+func init() {
+	Register("s3", func(ctx context.Context, cfg Config) (Backend, error) {
+		return NewS3(ctx, "s3", cfg)
+	})
+	Register("s3express", func(ctx context.Context, cfg Config) (Backend, error) {
+		b, err := NewS3(ctx, "s3express", cfg)
+		if err != nil {
+			return nil, err
+		}
+		b.zoneID = expressZoneID
+		return b, nil
+	})
+	Register("tigris", func(ctx context.Context, cfg Config) (Backend, error) {
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = tigrisEndpoint
+		}
+		return NewS3(ctx, "tigris", cfg, func(o *s3.Options) {
+			o.Region = "auto"
+			o.UsePathStyle = false
+		})
+	})
+	for _, name := range []string{"s3", "s3express", "tigris"} {
+		transportBackends[name] = true
+	}
+}
+
+// S3 adapts the aws-sdk-go-v2 S3 client, and any S3-compatible endpoint such
+// as Tigris, to the Backend interface.
+type S3 struct {
+	name   string
+	client *s3.Client
+	zoneID string // non-empty for S3 Express One Zone directory buckets
+}
+
+// NewS3 loads the default AWS configuration and builds an S3 client for cfg.
+// optFns are applied after the region, endpoint and transport from cfg.
+func NewS3(ctx context.Context, name string, cfg Config, optFns ...func(*s3.Options)) (*S3, error) {
+	region := cfg.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		cfg.Transport.apply(o)
+		// The runner retries operations itself, under the same policy for
+		// every backend, so each attempt is counted.
+		o.Retryer = aws.NopRetryer{}
+		withTracing(o)
+		for _, fn := range optFns {
+			fn(o)
+		}
+	})
+	return &S3{name: name, client: client}, nil
+}
+
+// Client returns the underlying SDK client.
+func (b *S3) Client() *s3.Client { return b.client }
+
+// Location resolves the endpoint the same way the SDK does for a request,
+// so it is the virtual-hosted or zonal endpoint when bucket is set.
+func (b *S3) Location(bucket string) (region, endpoint string) {
+	o := b.client.Options()
+	params := s3.EndpointParameters{
+		Region:         aws.String(o.Region),
+		Endpoint:       o.BaseEndpoint,
+		ForcePathStyle: aws.Bool(o.UsePathStyle),
+	}
+	if bucket != "" {
+		params.Bucket = aws.String(bucket)
+	} else if b.zoneID != "" {
+		params.UseS3ExpressControlEndpoint = aws.Bool(true)
+	}
+	ep, err := o.EndpointResolverV2.ResolveEndpoint(context.Background(), params.WithDefaults())
+	if err != nil {
+		return o.Region, aws.ToString(o.BaseEndpoint)
+	}
+	return o.Region, ep.URI.String()
+}
+
+// ResolveCredentials fills the client's credential cache, which otherwise
+// happens during the first request.
+func (b *S3) ResolveCredentials(ctx context.Context) error {
+	creds := b.client.Options().Credentials
+	if creds == nil {
+		return nil
+	}
+	if _, err := creds.Retrieve(ctx); err != nil {
+		return fmt.Errorf("failed to resolve credentials: %w", err)
+	}
+	return nil
+}
+
+func (b *S3) Name() string { return b.name }
+
+// BucketName appends the zone suffix required for directory buckets.
+func (b *S3) BucketName(base string) string {
+	if b.zoneID == "" {
+		return base
+	}
+	return fmt.Sprintf("%s--%s%s", base, b.zoneID, expressBucketTail)
+}
+
+func (b *S3) CreateBucket(ctx context.Context, bucket string) error {
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	if b.zoneID != "" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			Bucket: &types.BucketInfo{
+				Type:           types.BucketTypeDirectory,
+				DataRedundancy: types.DataRedundancySingleAvailabilityZone,
+			},
+			Location: &types.LocationInfo{
+				Name: aws.String(b.zoneID),
+				Type: types.LocationTypeAvailabilityZone,
+			},
+		}
+	}
+	_, err := b.client.CreateBucket(ctx, input)
+	return err
+}
+
+func (b *S3) DeleteBucket(ctx context.Context, bucket string) error {
+	_, err := b.client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)})
+	return err
+}
+
+// RemoveBucket aborts incomplete multipart uploads and deletes every object
+// before deleting the bucket.
+func (b *S3) RemoveBucket(ctx context.Context, bucket string) error {
+	return cleanup.S3Bucket(b.client, bucket)(ctx)
+}
+
+// MarkOwned tags bucket with the ownership marker. Directory buckets cannot
+// be tagged, so for S3 Express it does nothing.
+func (b *S3) MarkOwned(ctx context.Context, bucket string) error {
+	if b.zoneID != "" {
+		return nil
+	}
+	_, err := b.client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket: aws.String(bucket),
+		Tagging: &types.Tagging{TagSet: []types.Tag{{
+			Key:   aws.String(OwnerTagKey),
+			Value: aws.String(OwnerTagValue),
+		}}},
+	})
+	return err
+}
+
+// Owned reports whether bucket carries the ownership marker tag.
+func (b *S3) Owned(ctx context.Context, bucket string) (bool, error) {
+	if b.zoneID != "" {
+		return false, nil
+	}
+	out, err := b.client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchTagSet" {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, tag := range out.TagSet {
+		if aws.ToString(tag.Key) == OwnerTagKey && aws.ToString(tag.Value) == OwnerTagValue {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (b *S3) ListBuckets(ctx context.Context) ([]string, error) {
+	var names []string
+	if b.zoneID != "" {
+		paginator := s3.NewListDirectoryBucketsPaginator(b.client, &s3.ListDirectoryBucketsInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, bucket := range page.Buckets {
+				names = append(names, aws.ToString(bucket.Name))
+			}
+		}
+		return names, nil
+	}
+	paginator := s3.NewListBucketsPaginator(b.client, &s3.ListBucketsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, bucket := range page.Buckets {
+			names = append(names, aws.ToString(bucket.Name))
+		}
+	}
+	return names, nil
+}
+
+func (b *S3) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// PutObjectMetadata stores metadata as x-amz-meta-* headers.
+func (b *S3) PutObjectMetadata(ctx context.Context, bucket, key string, data []byte, metadata map[string]string) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(data),
+		Metadata: metadata,
+	})
+	return err
+}
+
+// PutObjectIfAbsent sends If-None-Match: *.
+func (b *S3) PutObjectIfAbsent(ctx context.Context, bucket, key string, data []byte) (string, error) {
+	return b.putConditional(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		IfNoneMatch: aws.String("*"),
+	})
+}
+
+// PutObjectIfMatch sends If-Match with etag.
+func (b *S3) PutObjectIfMatch(ctx context.Context, bucket, key string, data []byte, etag string) (string, error) {
+	return b.putConditional(ctx, &s3.PutObjectInput{
+		Bucket:  aws.String(bucket),
+		Key:     aws.String(key),
+		Body:    bytes.NewReader(data),
+		IfMatch: aws.String(etag),
+	})
+}
+
+// putConditional sends a conditional PUT. S3 answers 412 when the condition
+// does not hold and 409 when a concurrent conditional write to the key won;
+// both mean this write lost.
+func (b *S3) putConditional(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	resp, err := b.client.PutObject(ctx, input)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "PreconditionFailed", "ConditionalRequestConflict":
+				return "", fmt.Errorf("object %s/%s: %w: %w", *input.Bucket, *input.Key, ErrPreconditionFailed, err)
+			case "NotImplemented":
+				return "", fmt.Errorf("conditional write on %s: %w: %w", b.name, ErrUnsupported, err)
+			}
+		}
+		return "", err
+	}
+	return aws.ToString(resp.ETag), nil
+}
+
+// GetObject reads the whole body so the latency covers the full transfer.
+func (b *S3) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	resp, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, fmt.Errorf("object %s/%s: %w: %w", bucket, key, ErrNotFound, err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (b *S3) HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	resp, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var nf *types.NotFound
+		if errors.As(err, &nf) {
+			return ObjectInfo{}, fmt.Errorf("object %s/%s: %w: %w", bucket, key, ErrNotFound, err)
+		}
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Size: aws.ToInt64(resp.ContentLength), ETag: aws.ToString(resp.ETag), Metadata: resp.Metadata}, nil
+}
+
+func (b *S3) HeadBucket(ctx context.Context, bucket string) error {
+	_, err := b.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	return err
+}
+
+func (b *S3) CopyObject(ctx context.Context, bucket, src, dst string) error {
+	_, err := b.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(copySource(bucket, src)),
+	})
+	return err
+}
+
+// CopyObjectParts copies src with UploadPartCopy, up to partCopyConcurrency
+// parts at a time, and aborts the upload if any part fails.
+func (b *S3) CopyObjectParts(ctx context.Context, bucket, src, dst string, size, partSize int64) error {
+	up, err := b.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(dst),
+	})
+	if err != nil {
+		return err
+	}
+	upload := cleanup.Track(ctx, cleanup.Upload, dst, cleanup.S3Upload(b.client, bucket, dst, aws.ToString(up.UploadId)))
+	n := int((size + partSize - 1) / partSize)
+	parts := make([]types.CompletedPart, n)
+	errs := make([]error, n)
+	sem := make(chan struct{}, partCopyConcurrency)
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			first := int64(i) * partSize
+			last := min(size, first+partSize) - 1
+			out, err := b.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+				Bucket:          aws.String(bucket),
+				Key:             aws.String(dst),
+				UploadId:        up.UploadId,
+				PartNumber:      aws.Int32(int32(i + 1)),
+				CopySource:      aws.String(copySource(bucket, src)),
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+			})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			parts[i] = types.CompletedPart{ETag: out.CopyPartResult.ETag, PartNumber: aws.Int32(int32(i + 1))}
+		}()
+	}
+	wg.Wait()
+	if i := slices.IndexFunc(errs, func(err error) bool { return err != nil }); i >= 0 {
+		err = errs[i]
+	} else {
+		_, err = b.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(dst),
+			UploadId:        up.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+	}
+	if err != nil {
+		// Abort even if ctx is done, so the parts are not left billed. A
+		// retry starts a fresh upload.
+		upload.Remove(ctx)
+		return err
+	}
+	upload.Forget()
+	return nil
+}
+
+// copySource names key in bucket as CopySource expects: "bucket/key",
+// URL-encoded.
+func copySource(bucket, key string) string {
+	return (&url.URL{Path: bucket + "/" + key}).EscapedPath()
+}
+
+func (b *S3) DeleteObject(ctx context.Context, bucket, key string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (b *S3) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(b.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+func (b *S3) Close() error { return nil }
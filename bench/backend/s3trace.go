@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/tracing"
+)
+
+// withTracing adds a span per SDK attempt, nested under the benchmark
+// operation span, and a span per HTTP round trip under each attempt. Without
+// a tracer provider installed the spans are no-ops.
+func withTracing(o *s3.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// After the retry middleware, so it runs once per attempt.
+		return stack.Finalize.Insert(attemptSpan{}, "Retry", middleware.After)
+	})
+	next := o.HTTPClient
+	if next == nil {
+		next = awshttp.NewBuildableClient()
+	}
+	o.HTTPClient = &tracingHTTPClient{next: next}
+}
+
+// attemptSpan is a finalize middleware that wraps one attempt in a span.
+type attemptSpan struct{}
+
+func (attemptSpan) ID() string { return "BenchAttemptSpan" }
+
+func (attemptSpan) HandleFinalize(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (
+	middleware.FinalizeOutput, middleware.Metadata, error,
+) {
+	name := awsmiddleware.GetServiceID(ctx) + "." + awsmiddleware.GetOperationName(ctx)
+	ctx, span := tracing.StartAttempt(ctx, name)
+	defer span.End()
+	out, md, err := next.HandleFinalize(ctx, in)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return out, md, err
+}
+
+// tracingHTTPClient records each HTTP round trip as a client span. The span
+// ends when the response headers arrive; body transfer is covered by the
+// operation span.
+type tracingHTTPClient struct {
+	next s3.HTTPClient
+}
+
+func (c *tracingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("url.path", req.URL.Path),
+			attribute.Int64("http.request.body.size", req.ContentLength),
+		))
+	defer span.End()
+	resp, err := c.next.Do(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
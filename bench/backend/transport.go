@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Transport tunes the HTTP client of the S3-compatible backends. The zero
+// value keeps the SDK defaults: 10 idle connections per host, keep-alive,
+// HTTP/2 where the server offers it, 4KB buffers and transparent gzip.
+type Transport struct {
+	MaxIdleConnsPerHost int  `json:"max_idle_conns_per_host,omitempty"`
+	DisableKeepAlives   bool `json:"disable_keep_alives,omitempty"`
+	DisableHTTP2        bool `json:"disable_http2,omitempty"`
+	ReadBufferSize      int  `json:"read_buffer_size,omitempty"`
+	WriteBufferSize     int  `json:"write_buffer_size,omitempty"`
+	DisableCompression  bool `json:"disable_compression,omitempty"`
+}
+
+// String describes t compactly, e.g. "idle=100 keepalive=on http2=off
+// rbuf=64KB wbuf=default compression=on".
+func (t Transport) String() string {
+	onOff := func(disabled bool) string {
+		if disabled {
+			return "off"
+		}
+		return "on"
+	}
+	size := func(n int) string {
+		switch {
+		case n == 0:
+			return "default"
+		case n%1024 == 0:
+			return fmt.Sprintf("%dKB", n/1024)
+		}
+		return fmt.Sprint(n)
+	}
+	idle := "default"
+	if t.MaxIdleConnsPerHost > 0 {
+		idle = fmt.Sprint(t.MaxIdleConnsPerHost)
+	}
+	return strings.Join([]string{
+		"idle=" + idle,
+		"keepalive=" + onOff(t.DisableKeepAlives),
+		"http2=" + onOff(t.DisableHTTP2),
+		"rbuf=" + size(t.ReadBufferSize),
+		"wbuf=" + size(t.WriteBufferSize),
+		"compression=" + onOff(t.DisableCompression),
+	}, " ")
+}
+
+// apply installs an SDK HTTP client configured by t. It must run before
+// withTracing, which wraps whatever client is installed.
+func (t Transport) apply(o *s3.Options) {
+	if t == (Transport{}) {
+		return
+	}
+	o.HTTPClient = awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if t.MaxIdleConnsPerHost > 0 {
+			tr.MaxIdleConnsPerHost = t.MaxIdleConnsPerHost
+			tr.MaxIdleConns = max(tr.MaxIdleConns, t.MaxIdleConnsPerHost)
+		}
+		tr.DisableKeepAlives = t.DisableKeepAlives
+		if t.DisableHTTP2 {
+			tr.ForceAttemptHTTP2 = false
+			// A non-nil map stops net/http from negotiating h2 itself.
+			tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+		if t.ReadBufferSize > 0 {
+			tr.ReadBufferSize = t.ReadBufferSize
+		}
+		if t.WriteBufferSize > 0 {
+			tr.WriteBufferSize = t.WriteBufferSize
+		}
+		tr.DisableCompression = t.DisableCompression
+	})
+}
@@ -0,0 +1,295 @@
+// Package cleanup records the buckets, objects and multipart uploads a
+// benchmark creates and makes sure they are removed however the program
+// ends: on normal return, on os.Exit through Tracker.Exit, after SIGINT or
+// SIGTERM, and on panic. Anything that cannot be removed is reported, so it
+// can be deleted by hand or with "bench sweep".
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Timeout bounds each removal, so a hung backend cannot keep a stopping
+// program alive forever.
+const Timeout = 5 * time.Minute
+
+// ErrInterrupted is the cause of the tracker's context when the program
+// receives SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("interrupted")
+
+// Kind is the type of a tracked resource.
+type Kind string
+
+const (
+	Bucket Kind = "bucket"
+	Object Kind = "object"
+	Upload Kind = "multipart upload"
+)
+
+// Resource is something the program created and must remove.
+type Resource struct {
+	Kind Kind
+	Name string
+
+	t      *Tracker
+	id     int
+	parent *Resource
+	remove func(ctx context.Context) error
+}
+
+// Tracker removes every resource that is still tracked when the program
+// ends. A nil *Tracker tracks nothing, but Resource.Remove still works.
+type Tracker struct {
+	out    io.Writer
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	signal   os.Signal
+	next     int
+	live     map[int]*Resource
+	closed   bool
+	removing sync.WaitGroup
+	once     sync.Once
+}
+
+// New returns a tracker and a context derived from ctx that is canceled,
+// with a cause wrapping ErrInterrupted, when the program receives SIGINT or
+// SIGTERM. The program then stops its operations, saves what it has, and
+// returns to the deferred Close, which removes everything still tracked and
+// exits with the signal's status. A second signal skips straight to the
+// removal and exits, and a third exits at once.
+func New(ctx context.Context, out io.Writer) (*Tracker, context.Context) {
+	if out == nil {
+		out = os.Stdout
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	t := &Tracker{out: out, cancel: cancel, live: make(map[int]*Resource)}
+
+	sigs := make(chan os.Signal, 3)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		t.mu.Lock()
+		t.signal = sig
+		t.mu.Unlock()
+		fmt.Fprintf(t.out, "\nReceived %s, stopping and saving partial results (send it again to clean up and exit)\n", sig)
+		cancel(fmt.Errorf("%w by %s", ErrInterrupted, sig))
+
+		<-sigs
+		fmt.Fprintf(t.out, "\nReceived %s again, cleaning up (send it again to exit immediately)\n", sig)
+		go func() {
+			<-sigs
+			os.Exit(exitCode(sig))
+		}()
+		t.teardown()
+		os.Exit(exitCode(sig))
+	}()
+	return t, ctx
+}
+
+// Interrupted reports whether ctx, from New, was canceled by a signal.
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
+
+// exitCode follows the shell convention of 128 plus the signal number.
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// Track records a resource and how to remove it. remove must delete
+// everything the resource holds, e.g. every object and upload in a bucket.
+func (t *Tracker) Track(kind Kind, name string, remove func(ctx context.Context) error) *Resource {
+	return t.track(&Resource{Kind: kind, Name: name, t: t, remove: remove})
+}
+
+// Track records a resource held by r, such as an object in a bucket. It is
+// forgotten once r is removed, since removing r removes it too.
+func (r *Resource) Track(kind Kind, name string, remove func(ctx context.Context) error) *Resource {
+	return r.t.track(&Resource{Kind: kind, Name: name, t: r.t, parent: r, remove: remove})
+}
+
+func (t *Tracker) track(r *Resource) *Resource {
+	if t == nil {
+		return r
+	}
+	t.mu.Lock()
+	closed := t.closed
+	if !closed {
+		t.next++
+		r.id = t.next
+		t.live[r.id] = r
+	}
+	t.mu.Unlock()
+	if closed {
+		// Created while the tracker was shutting down.
+		t.removeNow(r)
+	}
+	return r
+}
+
+// Remove deletes the resource now and stops tracking it. If removal fails
+// the resource stays tracked, so the tracker tries again on exit. Removal is
+// not canceled with ctx.
+func (r *Resource) Remove(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	t := r.t
+	if t == nil {
+		return r.run(ctx)
+	}
+	t.mu.Lock()
+	if t.closed || t.live[r.id] == nil {
+		// Already removed, or the tracker is removing it.
+		t.mu.Unlock()
+		return nil
+	}
+	delete(t.live, r.id)
+	t.removing.Add(1)
+	t.mu.Unlock()
+	defer t.removing.Done()
+
+	err := r.run(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case err == nil:
+		for id, child := range t.live {
+			if child.parent == r {
+				delete(t.live, id)
+			}
+		}
+	case t.closed:
+		fmt.Fprintf(t.out, "Could not remove %s %s: %v\n", r.Kind, r.Name, err)
+	default:
+		t.live[r.id] = r
+	}
+	return err
+}
+
+// Forget stops tracking the resource without removing it, because the
+// program deleted it itself or handed it on, e.g. a completed upload.
+func (r *Resource) Forget() {
+	if r == nil || r.t == nil {
+		return
+	}
+	r.t.mu.Lock()
+	delete(r.t.live, r.id)
+	r.t.mu.Unlock()
+}
+
+type resourceKey struct{}
+
+// WithResource returns a context carrying r, so code that creates resources
+// inside it, such as a backend starting a multipart upload, can track them
+// under r with Track.
+func WithResource(ctx context.Context, r *Resource) context.Context {
+	return context.WithValue(ctx, resourceKey{}, r)
+}
+
+// Track records a resource held by the resource ctx carries. Without one it
+// tracks nothing, but Resource.Remove still works.
+func Track(ctx context.Context, kind Kind, name string, remove func(ctx context.Context) error) *Resource {
+	if r, ok := ctx.Value(resourceKey{}).(*Resource); ok && r != nil {
+		return r.Track(kind, name, remove)
+	}
+	return (*Tracker)(nil).Track(kind, name, remove)
+}
+
+func (r *Resource) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), Timeout)
+	defer cancel()
+	return r.remove(ctx)
+}
+
+// Close removes every resource still tracked. Defer it right after New: if
+// the program is panicking, Close cleans up and then resumes the panic, and
+// if a signal stopped it, Close cleans up and then exits with the signal's
+// status.
+func (t *Tracker) Close() {
+	if r := recover(); r != nil {
+		if t != nil {
+			fmt.Fprintf(t.out, "\npanic: %v\n", r)
+			t.teardown()
+		}
+		panic(r)
+	}
+	t.teardown()
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	sig := t.signal
+	t.mu.Unlock()
+	if sig != nil {
+		os.Exit(exitCode(sig))
+	}
+}
+
+// Exit removes every resource still tracked and exits with code. Use it in
+// place of os.Exit, which skips deferred cleanup.
+func (t *Tracker) Exit(code int) {
+	t.teardown()
+	os.Exit(code)
+}
+
+// teardown removes the remaining resources, newest first so uploads and
+// objects go before the buckets holding them, and reports what is left.
+func (t *Tracker) teardown() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		t.cancel(nil)
+		t.mu.Lock()
+		t.closed = true
+		live := make([]*Resource, 0, len(t.live))
+		for id := t.next; id > 0; id-- {
+			if r := t.live[id]; r != nil {
+				live = append(live, r)
+			}
+		}
+		t.live = nil
+		t.mu.Unlock()
+		// Let removals that are already running finish first.
+		t.removing.Wait()
+
+		if len(live) == 0 {
+			return
+		}
+		fmt.Fprintf(t.out, "\nCleaning up %d leftover resources\n", len(live))
+		var failed []*Resource
+		for _, r := range live {
+			fmt.Fprintf(t.out, "Removing %s: %s\n", r.Kind, r.Name)
+			if err := r.run(context.Background()); err != nil {
+				fmt.Fprintf(t.out, "Failed to remove %s %s: %v\n", r.Kind, r.Name, err)
+				failed = append(failed, r)
+			}
+		}
+		if len(failed) > 0 {
+			fmt.Fprintf(t.out, "\nCould not remove %d resources; delete them by hand or with \"bench sweep\":\n", len(failed))
+			for _, r := range failed {
+				fmt.Fprintf(t.out, "  %s %s\n", r.Kind, r.Name)
+			}
+		}
+	})
+}
+
+// removeNow removes a resource created after teardown started.
+func (t *Tracker) removeNow(r *Resource) {
+	if err := r.run(context.Background()); err != nil {
+		fmt.Fprintf(t.out, "Could not remove %s %s: %v\n", r.Kind, r.Name, err)
+	}
+}
@@ -0,0 +1,143 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// removals records the order resources are removed in.
+type removals struct {
+	names []string
+	// fail makes the removal of the named resource fail while set.
+	fail map[string]bool
+}
+
+func (rm *removals) remove(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if rm.fail[name] {
+			return errors.New("removal failed")
+		}
+		rm.names = append(rm.names, name)
+		return nil
+	}
+}
+
+func TestTrackerRemovalOrder(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// run tracks and removes resources before the tracker closes.
+		run  func(tr *Tracker, rm *removals)
+		want []string
+	}{
+		{
+			name: "newest first",
+			run: func(tr *Tracker, rm *removals) {
+				a := tr.Track(Bucket, "a", rm.remove("a"))
+				a.Track(Object, "a/1", rm.remove("a/1"))
+				b := tr.Track(Bucket, "b", rm.remove("b"))
+				b.Track(Upload, "b/up", rm.remove("b/up"))
+				a.Track(Object, "a/2", rm.remove("a/2"))
+			},
+			want: []string{"a/2", "b/up", "b", "a/1", "a"},
+		},
+		{
+			name: "removed during the run",
+			run: func(tr *Tracker, rm *removals) {
+				a := tr.Track(Bucket, "a", rm.remove("a"))
+				tr.Track(Bucket, "b", rm.remove("b"))
+				a.Remove(ctx)
+				a.Remove(ctx)
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "removing a bucket forgets its objects",
+			run: func(tr *Tracker, rm *removals) {
+				a := tr.Track(Bucket, "a", rm.remove("a"))
+				a.Track(Object, "a/1", rm.remove("a/1"))
+				tr.Track(Bucket, "b", rm.remove("b"))
+				a.Remove(ctx)
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "failed removal is retried on close",
+			run: func(tr *Tracker, rm *removals) {
+				a := tr.Track(Bucket, "a", rm.remove("a"))
+				tr.Track(Bucket, "b", rm.remove("b"))
+				rm.fail["a"] = true
+				a.Remove(ctx)
+				delete(rm.fail, "a")
+			},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "forgotten",
+			run: func(tr *Tracker, rm *removals) {
+				tr.Track(Bucket, "a", rm.remove("a"))
+				tr.Track(Upload, "up", rm.remove("up")).Forget()
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "tracked through the context",
+			run: func(tr *Tracker, rm *removals) {
+				a := tr.Track(Bucket, "a", rm.remove("a"))
+				Track(WithResource(ctx, a), Upload, "a/up", rm.remove("a/up"))
+				Track(ctx, Upload, "untracked", rm.remove("untracked"))
+			},
+			want: []string{"a/up", "a"},
+		},
+		{
+			name: "still failing on close",
+			run: func(tr *Tracker, rm *removals) {
+				tr.Track(Bucket, "a", rm.remove("a"))
+				tr.Track(Bucket, "b", rm.remove("b"))
+				tr.Track(Bucket, "c", rm.remove("c"))
+				rm.fail["b"] = true
+			},
+			want: []string{"c", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := New(ctx, io.Discard)
+			rm := &removals{fail: make(map[string]bool)}
+			tt.run(tracker, rm)
+			tracker.Close()
+			if !slices.Equal(rm.names, tt.want) {
+				t.Errorf("removed %v, want %v", rm.names, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackAfterClose(t *testing.T) {
+	tracker, ctx := New(context.Background(), io.Discard)
+	rm := &removals{}
+	tracker.Close()
+	if ctx.Err() == nil {
+		t.Error("context still live after Close")
+	}
+	tracker.Track(Bucket, "late", rm.remove("late"))
+	if !slices.Equal(rm.names, []string{"late"}) {
+		t.Errorf("removed %v, want the late bucket at once", rm.names)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	rm := &removals{}
+	r := tracker.Track(Bucket, "a", rm.remove("a"))
+	tracker.Close()
+	if len(rm.names) != 0 {
+		t.Fatalf("nil tracker removed %v on close", rm.names)
+	}
+	if err := r.Remove(context.Background()); err != nil || !slices.Equal(rm.names, []string{"a"}) {
+		t.Errorf("Remove = %v, removed %v; want a removed", err, rm.names)
+	}
+}
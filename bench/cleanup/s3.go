@@ -0,0 +1,111 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3Bucket returns a remove function for a bucket on S3 or an S3-compatible
+// service, which empties the bucket completely and then deletes it.
+func S3Bucket(client *s3.Client, bucket string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := EmptyS3Bucket(ctx, client, bucket); err != nil {
+			return err
+		}
+		_, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)})
+		return err
+	}
+}
+
+// S3Object returns a remove function for one object.
+func S3Object(client *s3.Client, bucket, key string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		return err
+	}
+}
+
+// S3Upload returns a remove function that aborts a multipart upload.
+func S3Upload(client *s3.Client, bucket, key, uploadID string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: aws.String(uploadID),
+		})
+		return err
+	}
+}
+
+// EmptyS3Bucket aborts every incomplete multipart upload in bucket and
+// deletes every object, following all pages of both listings.
+func EmptyS3Bucket(ctx context.Context, client *s3.Client, bucket string) error {
+	var errs []error
+	uploads := s3.NewListMultipartUploadsPaginator(client, &s3.ListMultipartUploadsInput{Bucket: aws.String(bucket)})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if listingUploadsUnsupported(err) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list multipart uploads in %s: %w", bucket, err)
+		}
+		for _, u := range page.Uploads {
+			if err := S3Upload(client, bucket, aws.ToString(u.Key), aws.ToString(u.UploadId))(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to abort upload of %s: %w", aws.ToString(u.Key), err))
+			}
+		}
+	}
+
+	objects := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{Bucket: aws.String(bucket)})
+	for objects.HasMorePages() {
+		page, err := objects.NextPage(ctx)
+		if err != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to list objects in %s: %w", bucket, err))...)
+		}
+		// A page holds at most 1000 keys, the DeleteObjects limit.
+		ids := make([]types.ObjectIdentifier, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			ids = append(ids, types.ObjectIdentifier{Key: obj.Key})
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete objects in %s: %w", bucket, err))
+			continue
+		}
+		for _, e := range out.Errors {
+			errs = append(errs, fmt.Errorf("failed to delete object %s: %s", aws.ToString(e.Key), aws.ToString(e.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listingUploadsUnsupported reports whether err means the service does not
+// implement ListMultipartUploads. Some S3-compatible stores reject it outright
+// or answer NoSuchUpload when there is nothing to list.
+func listingUploadsUnsupported(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NotImplemented", "NoSuchUpload":
+		return true
+	}
+	return false
+}
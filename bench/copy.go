@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+)
+
+// copyCmd implements "bench copy".
+func copyCmd(args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	inst := instrumentFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	deadline := fs.Duration("deadline", 0, "stop the run after this long and report the operations completed so far; 0 means no deadline")
+	probes := probeFlags(fs)
+	sizes := []int{1 << 10, 1 << 20, 16 << 20, 64 << 20}
+	fs.Func("sizes", "comma-separated object sizes in bytes (default 1024,1048576,16777216,67108864)", intList(&sizes))
+	objects := fs.Int("objects", 10, "objects to write for each size; each is renamed once")
+	operations := fs.Int("operations", 50, "copies to make for each size")
+	threshold := fs.Int64("part-threshold", 16<<20, "copy objects of at least this many bytes in parts, where the backend can; 0 copies every object whole")
+	partSize := fs.Int64("part-size", 8<<20, "part size in bytes for multipart copies")
+	threads := fs.Int("threads", 10, "concurrent workers")
+	seed := fs.Int64("seed", 1, "random seed for payloads and source choice")
+	out := fs.String("out", "", "write the structured result as JSON to this file")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench copy --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	c := &runner.Copy{
+		Sizes:         sizes,
+		Objects:       *objects,
+		Operations:    *operations,
+		PartThreshold: *threshold,
+		PartSize:      *partSize,
+		Threads:       *threads,
+		Seed:          *seed,
+		Options: runner.Options{
+			Retry:    *retries,
+			Timeout:  *timeouts,
+			Deadline: *deadline,
+			Probe:    probes.config(),
+		},
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Running copy benchmark on %s\n", cfg.Name)
+		usage := cost.EstimateCopy(sizes, *objects, *operations, *threshold, *partSize)
+		return costs.printEstimate(os.Stdout, cfg.Name, probes.usage(usage), 1)
+	}
+	plan, err := inst.profilePlan(*out)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(runner.PhaseLoad, runner.PhaseCopy, runner.PhaseRename); err != nil {
+		return err
+	}
+
+	progress.Enabled = *showProgress
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := probes.check(b); err != nil {
+		return err
+	}
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Running copy benchmark on %s\n", b.Name())
+	fmt.Println("======================================")
+
+	if err := inst.start(ctx); err != nil {
+		return err
+	}
+	defer inst.finish(ctx)
+
+	c.Backend = b
+	c.Metrics = inst.exporter
+	c.Profile = plan
+	c.Cleanup = tracker
+	defer c.Close(ctx)
+	res, err := c.Run(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+)
+
+// costFlags holds the flags that price a run.
+type costFlags struct {
+	estimate *bool
+	pricing  *string
+	egress   *bool
+
+	m *cost.Model
+}
+
+// pricingFlags registers the flags shared by every command that runs a
+// workload.
+func pricingFlags(fs *flag.FlagSet) *costFlags {
+	return &costFlags{
+		estimate: fs.Bool("estimate", false, "print the expected requests and cost, then exit without running anything"),
+		pricing:  fs.String("pricing", "", "pricing model JSON file (default: the built-in model for the backend)"),
+		egress:   fs.Bool("egress", false, "include data transfer out, for clients outside the backend's region"),
+	}
+}
+
+// load picks the pricing model for the backend. It is called before the run
+// so a bad --pricing file fails early; backends without a model are left
+// unpriced.
+func (f *costFlags) load(backendName string) error {
+	if *f.pricing != "" {
+		m, err := cost.Load(*f.pricing)
+		f.m = m
+		return err
+	}
+	f.m, _ = cost.ForBackend(backendName)
+	return nil
+}
+
+// printEstimate prices the expected usage of agents runs and prints it.
+func (f *costFlags) printEstimate(out io.Writer, backendName string, u cost.Usage, agents int) error {
+	if f.m == nil {
+		return fmt.Errorf("no pricing model for backend %s; pass --pricing <file>", backendName)
+	}
+	rep := f.m.Price(u.Scale(agents), *f.egress)
+	rep.Estimate = true
+	rep.Agents = agents
+	rep.Print(out)
+	return nil
+}
+
+// report prices a finished run, prints the report and adds it to res. A
+// run on a backend without a model says how to price it instead.
+func (f *costFlags) report(out io.Writer, res *runner.Result) {
+	if f.m == nil {
+		fmt.Fprintf(out, "\nNo pricing model for backend %s; pass --pricing <file> to report the run's cost\n", res.Backend)
+		return
+	}
+	rep := f.m.Price(res.Usage(), *f.egress)
+	rep.Agents = res.Agents
+	rep.Print(out)
+	res.Cost = &rep
+}
@@ -0,0 +1,225 @@
+// Package cost prices benchmark runs. A pricing model describes what one
+// backend charges for requests, storage and data transfer; a Usage counts
+// what a run does, either estimated from the workload before it starts or
+// taken from its result afterwards.
+package cost
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+//go:embed pricing/*.json
+var builtin embed.FS
+
+// Request is a billable request type. Bucket and cleanup calls are counted
+// under the type the services bill them as.
+type Request string
+
+const (
+	RequestPut    Request = "PUT"
+	RequestGet    Request = "GET"
+	RequestHead   Request = "HEAD"
+	RequestList   Request = "LIST"
+	RequestDelete Request = "DELETE"
+)
+
+// requests lists every type a model must price, in report order.
+var requests = []Request{RequestPut, RequestGet, RequestHead, RequestList, RequestDelete}
+
+// gb is the gigabyte the services bill in.
+const gb = 1 << 30
+
+// hoursPerMonth converts monthly storage prices to GB-hours.
+const hoursPerMonth = 730
+
+// Model is one backend's price list in USD, read from a JSON file.
+type Model struct {
+	Backend     string `json:"backend"`
+	Description string `json:"description"`
+	// AsOf is when the prices were taken from Source; they change.
+	AsOf              string  `json:"as_of"`
+	Source            string  `json:"source,omitempty"`
+	Classes           []Class `json:"classes"`
+	StoragePerGBMonth float64 `json:"storage_per_gb_month"`
+	// EgressPerGB is charged on data read by clients outside the backend's
+	// region; reads from the same region are free on every service here.
+	EgressPerGB float64 `json:"egress_per_gb"`
+}
+
+// Class is a group of request types billed at the same rate.
+type Class struct {
+	Name     string    `json:"name"`
+	Requests []Request `json:"requests"`
+	Per1000  float64   `json:"per_1000"`
+	// PerGB is charged on the bytes each request transfers beyond
+	// FreeBytes, as S3 Express One Zone does above 512KB.
+	PerGB     float64 `json:"per_gb,omitempty"`
+	FreeBytes int64   `json:"free_bytes,omitempty"`
+}
+
+// ForBackend returns the built-in model for a backend, if there is one.
+func ForBackend(name string) (*Model, bool) {
+	data, err := builtin.ReadFile("pricing/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	m, err := parse(data)
+	if err != nil {
+		panic(fmt.Sprintf("built-in pricing model %s: %v", name, err))
+	}
+	return m, true
+}
+
+// Load reads a model from a JSON file in the format of the built-in ones.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing model: %w", err)
+	}
+	m, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("pricing model %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// parse decodes a model and checks that it prices every request type once.
+func parse(data []byte) (*Model, error) {
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	seen := make(map[Request]string)
+	for _, c := range m.Classes {
+		for _, req := range c.Requests {
+			if prev, ok := seen[req]; ok {
+				return nil, fmt.Errorf("%s is priced by both %s and %s", req, prev, c.Name)
+			}
+			seen[req] = c.Name
+		}
+	}
+	for _, req := range requests {
+		if _, ok := seen[req]; !ok {
+			return nil, fmt.Errorf("no class prices %s requests", req)
+		}
+	}
+	return &m, nil
+}
+
+// Report is a priced Usage.
+type Report struct {
+	Model    string `json:"model"`
+	AsOf     string `json:"as_of"`
+	Estimate bool   `json:"estimate,omitempty"`
+	// Agents is how many agents a distributed run's usage covers.
+	Agents         int         `json:"agents,omitempty"`
+	Classes        []ClassCost `json:"classes"`
+	StorageGB      float64     `json:"storage_gb"`
+	StorageHours   float64     `json:"storage_hours"`
+	StorageUSD     float64     `json:"storage_usd"`
+	TransferOutGB  float64     `json:"transfer_out_gb"`
+	EgressUSD      float64     `json:"egress_usd"`
+	IncludesEgress bool        `json:"includes_egress"`
+	TotalUSD       float64     `json:"total_usd"`
+}
+
+// ClassCost is what one request class costs.
+type ClassCost struct {
+	Name     string  `json:"name"`
+	Requests int64   `json:"requests"`
+	USD      float64 `json:"usd"`
+}
+
+// Price computes what u costs under m. Egress is only charged when egress
+// is set, since benchmarks normally run in the backend's region.
+func (m *Model) Price(u Usage, egress bool) Report {
+	rep := Report{Model: m.Description, AsOf: m.AsOf, IncludesEgress: egress}
+	for _, c := range m.Classes {
+		cc := ClassCost{Name: c.Name}
+		for _, l := range u.Lines {
+			if !c.prices(l.Request) {
+				continue
+			}
+			cc.Requests += l.Count
+			if over := l.Size - c.FreeBytes; c.PerGB > 0 && over > 0 {
+				cc.USD += float64(l.Count) * float64(over) / gb * c.PerGB
+			}
+		}
+		cc.USD += float64(cc.Requests) / 1000 * c.Per1000
+		rep.Classes = append(rep.Classes, cc)
+		rep.TotalUSD += cc.USD
+	}
+
+	// Storage is billed by the hour, so even a short run pays for one.
+	rep.StorageGB = float64(u.StoredBytes) / gb
+	rep.StorageHours = math.Max(1, math.Ceil(u.Duration.Hours()))
+	rep.StorageUSD = rep.StorageGB * rep.StorageHours / hoursPerMonth * m.StoragePerGBMonth
+	rep.TotalUSD += rep.StorageUSD
+
+	rep.TransferOutGB = float64(u.TransferOut()) / gb
+	rep.EgressUSD = rep.TransferOutGB * m.EgressPerGB
+	if egress {
+		rep.TotalUSD += rep.EgressUSD
+	}
+	return rep
+}
+
+func (c Class) prices(req Request) bool {
+	for _, r := range c.Requests {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the report as a table.
+func (rep Report) Print(out io.Writer) {
+	title := "COST REPORT"
+	if rep.Estimate {
+		title = "COST ESTIMATE"
+	}
+	fmt.Fprintf(out, "\n===== %s =====\n", title)
+	fmt.Fprintf(out, "\nPricing: %s (as of %s)\n", rep.Model, rep.AsOf)
+	if rep.Agents > 1 {
+		fmt.Fprintf(out, "Covers all %d agents\n", rep.Agents)
+	}
+	for _, c := range rep.Classes {
+		fmt.Fprintf(out, "%-32s %14s requests %12s\n", c.Name, count(c.Requests), usd(c.USD))
+	}
+	fmt.Fprintf(out, "%-32s %10.3f GB for %3.0f h %12s\n", "Storage", rep.StorageGB, rep.StorageHours, usd(rep.StorageUSD))
+	if rep.IncludesEgress {
+		fmt.Fprintf(out, "%-32s %14.3f GB       %12s\n", "Data transfer out", rep.TransferOutGB, usd(rep.EgressUSD))
+	}
+	fmt.Fprintf(out, "%-32s %36s\n", "Total", usd(rep.TotalUSD))
+	if !rep.IncludesEgress && rep.EgressUSD > 0 {
+		fmt.Fprintf(out, "Reading from outside the region would add %s of data transfer (--egress)\n", usd(rep.EgressUSD))
+	}
+}
+
+// usd formats an amount with enough digits to show sub-cent costs.
+func usd(v float64) string {
+	if v != 0 && v < 1 {
+		return fmt.Sprintf("$%.6f", v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// count formats n with thousands separators.
+func count(n int64) string {
+	s := fmt.Sprint(n)
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+package cost
+
+import (
+	"math"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// Line is a number of requests of one type that each transfer Size bytes.
+type Line struct {
+	Request Request
+	Count   int64
+	Size    int64
+}
+
+// Usage is what a run does that a backend bills for.
+type Usage struct {
+	Lines []Line
+	// StoredBytes is the most data the run keeps in its buckets at once.
+	StoredBytes int64
+	// Duration is how long the data is kept; storage is billed for at
+	// least an hour.
+	Duration time.Duration
+}
+
+// Add counts n requests of size bytes each.
+func (u *Usage) Add(req Request, n, size int64) {
+	if n > 0 {
+		u.Lines = append(u.Lines, Line{Request: req, Count: n, Size: size})
+	}
+}
+
+// AddOp counts n operations named as in results: a workload operation such
+// as "Read-Modify-Write", or a trace operation such as "GET". It reports
+// false for operations it does not know.
+func (u *Usage) AddOp(op string, n, size int64) bool {
+	switch op {
+	case string(workload.OpRead), string(trace.OpGet):
+		u.Add(RequestGet, n, size)
+	case string(workload.OpUpdate), string(workload.OpInsert), string(trace.OpPut):
+		u.Add(RequestPut, n, size)
+	case string(workload.OpScan), string(trace.OpList):
+		u.Add(RequestList, n, 0)
+	case string(workload.OpReadModifyWrite):
+		u.Add(RequestGet, n, size)
+		u.Add(RequestPut, n, size)
+	case string(trace.OpHead), string(trace.OpHeadBucket):
+		u.Add(RequestHead, n, 0)
+	case string(trace.OpDelete):
+		u.Add(RequestDelete, n, 0)
+	default:
+		return false
+	}
+	return true
+}
+
+// AddCopy counts n copies of objects of size bytes made by method, one of
+// the backend copy methods: a CopyObject each, a multipart upload with one
+// part copy per partSize bytes, or a GET and a PUT.
+func (u *Usage) AddCopy(method string, n, size, partSize int64) {
+	switch method {
+	case backend.CopyServer:
+		u.Add(RequestPut, n, 0)
+	case backend.CopyParts:
+		// CreateMultipartUpload and CompleteMultipartUpload besides the
+		// parts.
+		u.Add(RequestPut, n*(2+(size+partSize-1)/partSize), 0)
+	case backend.CopyClient:
+		u.Add(RequestGet, n, size)
+		u.Add(RequestPut, n, size)
+	}
+}
+
+// AddBucket counts creating, tagging, emptying and deleting one bucket that
+// holds objects objects when it is cleaned up.
+func (u *Usage) AddBucket(objects int64) {
+	// One page of ListObjectsV2 and one DeleteObjects call per 1000 objects.
+	pages := max(1, (objects+999)/1000)
+	u.Add(RequestPut, 2, 0)
+	u.Add(RequestList, 1+pages, 0)
+	u.Add(RequestDelete, pages+1, 0)
+}
+
+// AddProbe counts a bandwidth probe that writes and reads back an object of
+// size bytes transfers times, then deletes it.
+func (u *Usage) AddProbe(transfers int, size int64) {
+	u.Add(RequestPut, int64(transfers), size)
+	u.Add(RequestGet, int64(transfers), size)
+	u.Add(RequestDelete, 1, 0)
+}
+
+// TransferOut returns the bytes clients read.
+func (u *Usage) TransferOut() int64 {
+	var n int64
+	for _, l := range u.Lines {
+		if l.Request == RequestGet {
+			n += l.Count * l.Size
+		}
+	}
+	return n
+}
+
+// EstimateWorkload returns the expected usage of running w once: the load
+// phase, the run phase split by the mix, and the run's bucket.
+func EstimateWorkload(w workload.Workload) Usage {
+	var u Usage
+	size := int64(w.ObjectSize)
+	u.AddOp(string(workload.OpInsert), int64(w.RecordCount), size)
+	objects := int64(w.RecordCount)
+	for op, share := range w.Mix.Shares() {
+		n := int64(math.Round(float64(w.OperationCount) * share))
+		u.AddOp(string(op), n, size)
+		if op == workload.OpInsert {
+			objects += n
+		}
+	}
+	u.StoredBytes = objects * size
+	u.AddBucket(objects)
+	return u
+}
+
+// EstimateTrace returns the expected usage of replaying records at speed:
+// the objects prepared before the replay, every record, and the bucket. The
+// stored bytes assume no object is deleted or overwritten.
+func EstimateTrace(records []trace.Record, speed float64) Usage {
+	var u Usage
+	var objects int64
+	for _, rec := range append(trace.Seeds(records), records...) {
+		if !u.AddOp(string(rec.Op), 1, rec.Size) {
+			continue
+		}
+		if rec.Op == trace.OpPut {
+			objects++
+			u.StoredBytes += rec.Size
+		}
+	}
+	if speed > 0 && len(records) > 1 {
+		span := records[len(records)-1].Time.Sub(records[0].Time)
+		u.Duration = time.Duration(float64(span) / speed)
+	}
+	u.AddBucket(objects)
+	return u
+}
+
+// EstimateMetadata returns the expected usage of a metadata run: objects
+// PUTs of size bytes, then operations each of the two HEADs, HEAD_BUCKET
+// and GET, and the bucket.
+func EstimateMetadata(objects, operations int, size int64) Usage {
+	var u Usage
+	u.AddOp(string(trace.OpPut), int64(objects), size)
+	u.AddOp(string(trace.OpHead), 2*int64(operations), 0)
+	u.AddOp(string(trace.OpHeadBucket), int64(operations), 0)
+	u.AddOp(string(trace.OpGet), int64(operations), size)
+	u.StoredBytes = int64(objects) * size
+	u.AddBucket(int64(objects))
+	return u
+}
+
+// EstimateCopy returns the expected usage of a copy run on a backend that
+// copies on the server: objects PUTs per size, operations copies per size,
+// one rename of every object, and the bucket.
+func EstimateCopy(sizes []int, objects, operations int, partThreshold, partSize int64) Usage {
+	var u Usage
+	for _, size := range sizes {
+		size := int64(size)
+		method := backend.CopyServer
+		if partThreshold > 0 && size >= partThreshold {
+			method = backend.CopyParts
+		}
+		u.Add(RequestPut, int64(objects), size)
+		u.AddCopy(method, int64(operations+objects), size, partSize)
+		u.Add(RequestDelete, int64(objects), 0)
+		u.StoredBytes += int64(objects+operations) * size
+	}
+	u.AddBucket(int64((objects + operations) * len(sizes)))
+	return u
+}
+
+// EstimateOverwrite returns the expected usage of an overwrite run on a
+// backend with conditional writes: the keys written and overwritten, the
+// overwrites read back, the racing writers and their reads, the
+// conditional writes of threads workers and races, and the bucket.
+func EstimateOverwrite(keys, operations, writers, rounds, threads int, size int64) Usage {
+	var u Usage
+	puts := keys + operations + rounds + rounds*writers + operations + threads + rounds*(2*writers+1)
+	u.Add(RequestPut, int64(puts), size)
+	u.Add(RequestGet, 2*int64(rounds), size)
+	// New objects: the keys, the conflict key, conditional creates of new
+	// keys, each worker's own key and two per race round.
+	objects := int64(keys + 1 + operations/4 + threads + 2*rounds)
+	u.StoredBytes = objects * size
+	u.AddBucket(objects)
+	return u
+}
+
+// Scale multiplies every request count and the stored bytes by n, for runs
+// where n agents each do the same work in their own bucket.
+func (u Usage) Scale(n int) Usage {
+	scaled := Usage{StoredBytes: u.StoredBytes * int64(n), Duration: u.Duration}
+	for _, l := range u.Lines {
+		l.Count *= int64(n)
+		scaled.Lines = append(scaled.Lines, l)
+	}
+	return scaled
+}
@@ -0,0 +1,173 @@
+package cost_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// requests sums u's request counts by type.
+func requests(u cost.Usage) map[cost.Request]int64 {
+	n := make(map[cost.Request]int64)
+	for _, l := range u.Lines {
+		n[l.Request] += l.Count
+	}
+	return n
+}
+
+// checkRequests compares the requests and reads estimated for a run with
+// those its result counted.
+func checkRequests(t *testing.T, estimate, actual cost.Usage) {
+	t.Helper()
+	if want, got := requests(estimate), requests(actual); !maps.Equal(got, want) {
+		t.Errorf("run made requests %v, estimate %v", got, want)
+	}
+	if got, want := actual.TransferOut(), estimate.TransferOut(); got != want {
+		t.Errorf("run read %d bytes, estimate %d", got, want)
+	}
+}
+
+// checkUsage compares the estimate of a run that only creates objects with
+// the usage its result counted.
+func checkUsage(t *testing.T, estimate cost.Usage, res *runner.Result) {
+	t.Helper()
+	actual := res.Usage()
+	checkRequests(t, estimate, actual)
+	if actual.StoredBytes != estimate.StoredBytes {
+		t.Errorf("run stored %d bytes, estimate %d", actual.StoredBytes, estimate.StoredBytes)
+	}
+}
+
+// scenario is a run that cleans up after itself with Close.
+type scenario interface {
+	Run(ctx context.Context) (*runner.Result, error)
+	Close(ctx context.Context)
+}
+
+func run(t *testing.T, s scenario) *runner.Result {
+	t.Helper()
+	ctx := context.Background()
+	defer s.Close(ctx)
+	res, err := s.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func quiet() runner.Options {
+	return runner.Options{Out: io.Discard}
+}
+
+func TestEstimateWorkload(t *testing.T) {
+	tests := []struct {
+		name string
+		mix  workload.Mix
+	}{
+		{"read", workload.Mix{Read: 1}},
+		{"update", workload.Mix{Update: 1}},
+		{"insert", workload.Mix{Insert: 1}},
+		{"scan", workload.Mix{Scan: 1}},
+		{"read-modify-write", workload.Mix{ReadModifyWrite: 1}},
+		{"no operations", workload.Mix{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := workload.Workload{Name: tt.name, RecordCount: 50, OperationCount: 200, ObjectSize: 512, Threads: 4, Mix: tt.mix}
+			if tt.mix == (workload.Mix{}) {
+				w.OperationCount = 0
+			}
+			res := run(t, &runner.Runner{Backend: backend.NewFake(), Workload: w, Seed: 1, Options: quiet()})
+			checkUsage(t, cost.EstimateWorkload(w), res)
+		})
+	}
+}
+
+func TestEstimateTrace(t *testing.T) {
+	at := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	var records []trace.Record
+	for i := range 20 {
+		key := fmt.Sprintf("k%d", i%5)
+		op := []trace.Op{trace.OpGet, trace.OpPut, trace.OpHead, trace.OpGet, trace.OpList, trace.OpHeadBucket}[i%6]
+		records = append(records, trace.Record{Time: at.Add(time.Duration(i) * time.Millisecond), Op: op, Key: key, Size: 256})
+	}
+	records = append(records, trace.Record{Time: at.Add(time.Second), Op: trace.OpDelete, Key: "k1"})
+	res := run(t, &runner.Replay{Backend: backend.NewFake(), Records: records, Concurrency: 4, Seed: 1, Options: quiet()})
+	// Stored bytes are left out: the estimate assumes nothing is deleted
+	// or overwritten.
+	checkRequests(t, cost.EstimateTrace(records, 0), res.Usage())
+}
+
+func TestEstimateMetadata(t *testing.T) {
+	m := &runner.Metadata{
+		Backend:       backend.NewFake(),
+		Objects:       10,
+		Size:          256,
+		MetadataSizes: []int{0, 128},
+		Operations:    40,
+		Threads:       4,
+		Seed:          1,
+		Options:       quiet(),
+	}
+	res := run(t, m)
+	checkUsage(t, cost.EstimateMetadata(m.Objects*len(m.MetadataSizes), m.Operations, int64(m.Size)), res)
+}
+
+func TestEstimateCopy(t *testing.T) {
+	tests := []struct {
+		name          string
+		sizes         []int
+		partThreshold int64
+	}{
+		{"whole", []int{256, 4096}, 0},
+		{"in parts", []int{256, 12 << 20}, 8 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &runner.Copy{
+				Backend:       backend.NewFake(),
+				Sizes:         tt.sizes,
+				Objects:       4,
+				Operations:    10,
+				PartThreshold: tt.partThreshold,
+				PartSize:      runner.MinPartSize,
+				Threads:       2,
+				Seed:          1,
+				Options:       quiet(),
+			}
+			res := run(t, c)
+			checkUsage(t, cost.EstimateCopy(c.Sizes, c.Objects, c.Operations, c.PartThreshold, c.PartSize), res)
+		})
+	}
+}
+
+func TestEstimateOverwrite(t *testing.T) {
+	o := &runner.Overwrite{
+		Backend:    backend.NewFake(),
+		Size:       256,
+		Keys:       5,
+		Operations: 40,
+		Writers:    3,
+		Rounds:     4,
+		Threads:    4,
+		Seed:       1,
+		Options:    quiet(),
+	}
+	res := run(t, o)
+	estimate, actual := cost.EstimateOverwrite(o.Keys, o.Operations, o.Writers, o.Rounds, o.Threads, int64(o.Size)), res.Usage()
+	checkRequests(t, estimate, actual)
+	// The result counts every plain write as stored, overwrites included,
+	// so it can only overstate what the estimate keeps.
+	if actual.StoredBytes < estimate.StoredBytes {
+		t.Errorf("run stored %d bytes, fewer than the %d estimated", actual.StoredBytes, estimate.StoredBytes)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/distributed"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+)
+
+// coordinatorCmd implements "bench coordinator".
+func coordinatorCmd(args []string) error {
+	fs := flag.NewFlagSet("coordinator", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	wf := workloadFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	agents := fs.Int("agents", 2, "number of agents that must register before the run starts")
+	listen := fs.String("listen", ":7700", "address agents connect to")
+	out := fs.String("out", "", "write the merged result as JSON to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench coordinator <preset> --backend <backend> --agents <n> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *agents < 1 {
+		return fmt.Errorf("--agents must be at least 1")
+	}
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	// The backend is opened by each agent, so only check the name here.
+	if !slices.Contains(backend.Names(), cfg.Name) {
+		return fmt.Errorf("--backend must be one of %s", strings.Join(backend.Names(), ", "))
+	}
+	w, err := wf.workload(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Workload %s (%s) on %s from %d agents\n", w.Name, w.Title, cfg.Name, *agents)
+		return costs.printEstimate(os.Stdout, cfg.Name, cost.EstimateWorkload(w), *agents)
+	}
+
+	c := &distributed.Coordinator{Agents: *agents, Backend: *cfg, Workload: w, Seed: *wf.seed, Retry: *retries, Timeout: *timeouts}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+	}
+	// Ctrl-C stops waiting; the merge of what the agents reported so far
+	// is still printed and written.
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+
+	srv := &http.Server{Handler: c.Handler()}
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			stop(fmt.Errorf("coordinator server stopped: %w", err))
+		}
+	}()
+	defer srv.Close()
+
+	fmt.Printf("Workload %s (%s) on %s from %d agents\n", w.Name, w.Title, cfg.Name, *agents)
+	fmt.Println("======================================")
+	fmt.Printf("Waiting for agents on %s\n", ln.Addr())
+
+	res, err := c.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
+
+// agentCmd implements "bench agent".
+func agentCmd(args []string) error {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	coordinator := fs.String("coordinator", "", "coordinator URL, e.g. http://10.0.0.5:7700")
+	name := fs.String("name", "", "name shown by the coordinator (default: hostname and process ID)")
+	interval := fs.Duration("report-interval", distributed.DefaultReportInterval, "how often to stream statistics to the coordinator")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench agent --coordinator <url> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *coordinator == "" {
+		return errors.New("--coordinator is required")
+	}
+	if !strings.Contains(*coordinator, "://") {
+		*coordinator = "http://" + *coordinator
+	}
+	if *name == "" {
+		host, _ := os.Hostname()
+		*name = fmt.Sprintf("%s/%d", host, os.Getpid())
+	}
+
+	progress.Enabled = *showProgress
+
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
+	a := &distributed.Agent{Coordinator: *coordinator, Name: *name, ReportInterval: *interval, Cleanup: tracker}
+	return a.Run(ctx)
+}
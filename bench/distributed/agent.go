@@ -0,0 +1,209 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+)
+
+// DefaultReportInterval is how often an agent streams its statistics.
+const DefaultReportInterval = time.Second
+
+// Agent runs the workload a coordinator hands out and streams its
+// statistics back while it runs.
+type Agent struct {
+	// Coordinator is the coordinator's base URL, e.g. "http://10.0.0.5:7700".
+	Coordinator string
+	// Name identifies the agent in the coordinator's output.
+	Name string
+	// ReportInterval is the time between streamed reports; zero means
+	// DefaultReportInterval.
+	ReportInterval time.Duration
+	// Out receives the local progress and metrics; nil means os.Stdout.
+	Out io.Writer
+	// Cleanup, when set, tracks the agent's bucket so it is removed even if
+	// the agent is interrupted.
+	Cleanup *cleanup.Tracker
+
+	client *http.Client
+	job    Job
+	env    *environment.Info
+
+	mu    sync.Mutex
+	phase string
+	set   *stats.Set
+}
+
+// Run registers with the coordinator, runs the job it receives and reports
+// the outcome. Failures are reported to the coordinator, which stops the
+// other agents.
+func (a *Agent) Run(ctx context.Context) error {
+	if a.Out == nil {
+		a.Out = os.Stdout
+	}
+	if a.ReportInterval <= 0 {
+		a.ReportInterval = DefaultReportInterval
+	}
+	a.Coordinator = strings.TrimSuffix(a.Coordinator, "/")
+	a.client = &http.Client{} // no timeout: registration and barriers block
+
+	fmt.Fprintf(a.Out, "Registering with coordinator %s\n", a.Coordinator)
+	if err := a.post(ctx, pathRegister, registration{Name: a.Name}, &a.job); err != nil {
+		return fmt.Errorf("failed to register: %w", err)
+	}
+	fmt.Fprintf(a.Out, "Running workload %s as agent %d of %d\n", a.job.Workload.Name, a.job.Agent, a.job.Agents)
+
+	err := a.run(ctx)
+	done := doneRequest{Agent: a.job.Agent, Environment: a.env}
+	if err != nil {
+		done.Error = err.Error()
+	}
+	// Report even when ctx was canceled by a signal, so the coordinator
+	// merges the operations this agent completed.
+	if postErr := a.post(context.WithoutCancel(ctx), pathDone, done, nil); postErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to report completion: %w", postErr))
+	}
+	return err
+}
+
+func (a *Agent) run(ctx context.Context) error {
+	b, err := backend.Open(ctx, a.job.Backend)
+	if err != nil {
+		return fmt.Errorf("failed to open backend %s: %w", a.job.Backend.Name, err)
+	}
+	defer b.Close()
+
+	streamCtx, stopStreaming := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.stream(streamCtx)
+	}()
+
+	r := &runner.Runner{
+		Backend:  b,
+		Workload: a.job.Workload,
+		Seed:     a.job.Seed,
+		Barrier:  a.barrier,
+		OnPhase:  a.startPhase,
+		Options: runner.Options{
+			Retry:   a.job.Retry,
+			Timeout: a.job.Timeout,
+			Out:     a.Out,
+			Cleanup: a.Cleanup,
+		},
+	}
+	defer r.Close(ctx)
+	res, err := r.Run(ctx)
+	if res != nil {
+		a.env = res.Environment
+	}
+	stopStreaming()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return a.finishPhase(context.WithoutCancel(ctx))
+}
+
+// barrier waits until every agent is ready to start phase.
+func (a *Agent) barrier(ctx context.Context, phase string) error {
+	fmt.Fprintf(a.Out, "Waiting for all agents to reach the %s phase\n", phase)
+	return a.post(ctx, pathBarrier, barrierRequest{Agent: a.job.Agent, Phase: phase}, nil)
+}
+
+// startPhase sends the final report of the previous phase and switches
+// streaming to the new one.
+func (a *Agent) startPhase(phase string, set *stats.Set) {
+	if err := a.finishPhase(context.Background()); err != nil {
+		fmt.Fprintf(a.Out, "Failed to report phase: %v\n", err)
+	}
+	a.mu.Lock()
+	a.phase, a.set = phase, set
+	a.mu.Unlock()
+}
+
+// finishPhase sends the final report of the current phase, if any.
+func (a *Agent) finishPhase(ctx context.Context) error {
+	a.mu.Lock()
+	phase, set := a.phase, a.set
+	a.phase, a.set = "", nil
+	a.mu.Unlock()
+	if set == nil {
+		return nil
+	}
+	return a.report(ctx, phase, set, true)
+}
+
+// stream reports the current phase's statistics every ReportInterval.
+func (a *Agent) stream(ctx context.Context) {
+	ticker := time.NewTicker(a.ReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			phase, set := a.phase, a.set
+			a.mu.Unlock()
+			if set == nil {
+				continue
+			}
+			if err := a.report(ctx, phase, set, false); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(a.Out, "Failed to stream report: %v\n", err)
+			}
+		}
+	}
+}
+
+func (a *Agent) report(ctx context.Context, phase string, set *stats.Set, final bool) error {
+	return a.post(ctx, pathReport, report{
+		Agent:      a.job.Agent,
+		Phase:      phase,
+		Final:      final,
+		Operations: set.Snapshots(),
+	}, nil)
+}
+
+// post sends body as JSON to the coordinator and decodes the response into
+// out unless out is nil.
+func (a *Agent) post(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Coordinator+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("coordinator: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
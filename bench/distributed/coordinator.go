@@ -0,0 +1,366 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// progressInterval is how often the coordinator prints aggregate progress.
+const progressInterval = 5 * time.Second
+
+// Coordinator hands a workload to a fixed number of agents, releases them
+// through each phase together and merges their results.
+type Coordinator struct {
+	Agents   int
+	Backend  backend.Config
+	Workload workload.Workload
+	// Seed is offset by the agent number, so agents write different payloads.
+	Seed int64
+	// Retry and Timeout are the policies every agent runs with.
+	Retry   retry.Policy
+	Timeout timeout.Policy
+	// Out receives progress and metrics; nil means os.Stdout.
+	Out io.Writer
+
+	once      sync.Once
+	mu        sync.Mutex
+	names     []string
+	ready     chan struct{} // closed when every agent has registered
+	barriers  map[string]*barrier
+	phases    []string
+	reports   map[string]map[int]report // phase -> agent -> latest report
+	finished  map[int]bool
+	env       *environment.Info
+	agentEnvs map[int]*environment.Info
+	allDone   chan struct{}
+	abort     chan struct{}
+	abortErr  error
+	startedAt time.Time
+}
+
+// barrier releases agents once all of them have arrived, counting agents
+// that already finished, e.g. after an interrupt, since they never will.
+type barrier struct {
+	phase    string
+	arrived  map[int]bool
+	release  chan struct{}
+	released bool
+}
+
+func (c *Coordinator) init() {
+	c.once.Do(func() {
+		if c.Out == nil {
+			c.Out = os.Stdout
+		}
+		c.ready = make(chan struct{})
+		c.barriers = make(map[string]*barrier)
+		c.reports = make(map[string]map[int]report)
+		c.finished = make(map[int]bool)
+		c.agentEnvs = make(map[int]*environment.Info)
+		c.allDone = make(chan struct{})
+		c.abort = make(chan struct{})
+	})
+}
+
+// Handler returns the HTTP handler agents talk to.
+func (c *Coordinator) Handler() http.Handler {
+	c.init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+pathRegister, c.handleRegister)
+	mux.HandleFunc("POST "+pathBarrier, c.handleBarrier)
+	mux.HandleFunc("POST "+pathReport, c.handleReport)
+	mux.HandleFunc("POST "+pathDone, c.handleDone)
+	return mux
+}
+
+// fail releases every waiting agent with err.
+func (c *Coordinator) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.abortErr == nil {
+		c.abortErr = err
+		close(c.abort)
+	}
+}
+
+// wait blocks until ch is closed, the run is aborted or the request ends.
+func (c *Coordinator) wait(r *http.Request, ch <-chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-c.abort:
+		return c.abortErr
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var reg registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	if len(c.names) >= c.Agents {
+		c.mu.Unlock()
+		http.Error(w, fmt.Sprintf("all %d agents have already registered", c.Agents), http.StatusConflict)
+		return
+	}
+	agent := len(c.names)
+	c.names = append(c.names, reg.Name)
+	fmt.Fprintf(c.Out, "Agent %d registered from %s (%d/%d)\n", agent, reg.Name, agent+1, c.Agents)
+	if len(c.names) == c.Agents {
+		c.startedAt = time.Now()
+		close(c.ready)
+	}
+	c.mu.Unlock()
+
+	if err := c.wait(r, c.ready); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, Job{
+		Agent:    agent,
+		Agents:   c.Agents,
+		Backend:  c.Backend,
+		Workload: c.Workload,
+		Seed:     c.Seed + int64(agent),
+		Retry:    c.Retry,
+		Timeout:  c.Timeout,
+	})
+}
+
+func (c *Coordinator) handleBarrier(w http.ResponseWriter, r *http.Request) {
+	var req barrierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	b, ok := c.barriers[req.Phase]
+	if !ok {
+		b = &barrier{phase: req.Phase, arrived: make(map[int]bool), release: make(chan struct{})}
+		c.barriers[req.Phase] = b
+		c.phases = append(c.phases, req.Phase)
+	}
+	b.arrived[req.Agent] = true
+	c.releaseBarrier(b)
+	c.mu.Unlock()
+
+	if err := c.wait(r, b.release); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// releaseBarrier releases b once every agent has arrived at it or finished.
+// c.mu must be held.
+func (c *Coordinator) releaseBarrier(b *barrier) {
+	if b.released {
+		return
+	}
+	n := len(b.arrived)
+	for agent := range c.finished {
+		if !b.arrived[agent] {
+			n++
+		}
+	}
+	if n < c.Agents {
+		return
+	}
+	running := len(b.arrived)
+	if running == c.Agents {
+		fmt.Fprintf(c.Out, "\n===== %s PHASE (%d agents) =====\n", strings.ToUpper(b.phase), c.Agents)
+	} else {
+		fmt.Fprintf(c.Out, "\n===== %s PHASE (%d of %d agents) =====\n", strings.ToUpper(b.phase), running, c.Agents)
+	}
+	b.released = true
+	close(b.release)
+}
+
+func (c *Coordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+	var rep report
+	if err := json.NewDecoder(r.Body).Decode(&rep); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reports[rep.Phase] == nil {
+		c.reports[rep.Phase] = make(map[int]report)
+	}
+	// Reports may arrive out of order; never replace a final one.
+	if prev, ok := c.reports[rep.Phase][rep.Agent]; !ok || !prev.Final {
+		c.reports[rep.Phase][rep.Agent] = rep
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Coordinator) handleDone(w http.ResponseWriter, r *http.Request) {
+	var req doneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	if req.Error != "" {
+		fmt.Fprintf(c.Out, "Agent %d failed: %s\n", req.Agent, req.Error)
+		c.fail(fmt.Errorf("agent %d failed: %s", req.Agent, req.Error))
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished[req.Agent] {
+		return
+	}
+	c.finished[req.Agent] = true
+	c.agentEnvs[req.Agent] = req.Environment
+	fmt.Fprintf(c.Out, "Agent %d finished (%d/%d)\n", req.Agent, len(c.finished), c.Agents)
+	for _, phase := range c.phases {
+		c.releaseBarrier(c.barriers[phase])
+	}
+	if len(c.finished) == c.Agents {
+		close(c.allDone)
+	}
+}
+
+// Wait blocks until every agent has finished, printing aggregate progress on
+// the way, and returns the merged result. If ctx is canceled by a signal (see
+// cleanup.New), the agents are released and Wait returns what they reported
+// so far, marked interrupted; any other cancellation is returned as its
+// cause.
+func (c *Coordinator) Wait(ctx context.Context) (*runner.Result, error) {
+	c.init()
+	env := environment.Capture(ctx, nil)
+	env.Backend = &environment.Backend{Name: c.Backend.Name, Region: c.Backend.Region, Endpoint: c.Backend.Endpoint}
+	c.mu.Lock()
+	c.env = env
+	c.mu.Unlock()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.allDone:
+			return c.result(), nil
+		case <-c.abort:
+			return nil, c.abortErr
+		case <-ctx.Done():
+			err := context.Cause(ctx)
+			c.fail(err)
+			if !cleanup.Interrupted(ctx) {
+				return nil, err
+			}
+			fmt.Fprintf(c.Out, "\nRun interrupted: the results cover the reports received before it was stopped\n")
+			res := c.result()
+			res.Interrupted = true
+			return res, nil
+		case <-ticker.C:
+			c.printProgress()
+		}
+	}
+}
+
+// printProgress prints the combined operation count of the current phase.
+func (c *Coordinator) printProgress() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.phases) == 0 {
+		return
+	}
+	phase := c.phases[len(c.phases)-1]
+	var ops, errs int
+	for _, rep := range c.reports[phase] {
+		for _, snap := range rep.Operations {
+			if snap.Key.Variant == "" {
+				ops += int(snap.Latency.Total)
+				errs += snap.Errors
+			}
+		}
+	}
+	fmt.Fprintf(c.Out, "[%s phase] %d ops from %d agents | errors %d | elapsed %s\n",
+		phase, ops, len(c.reports[phase]), errs, time.Since(c.startedAt).Round(time.Second))
+}
+
+// result merges the final reports of every agent, phase by phase.
+func (c *Coordinator) result() *runner.Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := &runner.Result{
+		Backend:     c.Backend.Name,
+		Scenario:    runner.PresetScenario(c.Workload),
+		Workload:    c.Workload,
+		Seed:        c.Seed,
+		Retry:       c.Retry,
+		Timeout:     c.Timeout,
+		Agents:      c.Agents,
+		Environment: c.env,
+		StartedAt:   c.startedAt,
+		FinishedAt:  time.Now(),
+	}
+	for agent := range c.Agents {
+		res.AgentEnvironments = append(res.AgentEnvironments, c.agentEnvs[agent])
+	}
+	for _, phase := range c.phases {
+		agents := make([]int, 0, len(c.reports[phase]))
+		for agent := range c.reports[phase] {
+			agents = append(agents, agent)
+		}
+		slices.Sort(agents)
+		var groups [][]stats.Snapshot
+		for _, agent := range agents {
+			groups = append(groups, c.reports[phase][agent].Operations)
+		}
+		pr := runner.PhaseResult{Name: phase, Operations: stats.Compact(stats.Merge(groups...))}
+		for agent := range c.Agents {
+			if !c.reports[phase][agent].Final && !c.finished[agent] {
+				// Still running when the coordinator stopped.
+				pr.Incomplete = true
+			}
+		}
+		var first, last time.Time
+		for _, group := range groups {
+			for _, snap := range group {
+				if !snap.First.IsZero() && (first.IsZero() || snap.First.Before(first)) {
+					first = snap.First
+				}
+				if snap.Last.After(last) {
+					last = snap.Last
+				}
+			}
+		}
+		pr.DurationMs = float64(last.Sub(first).Nanoseconds()) / 1e6
+		res.Phases = append(res.Phases, pr)
+
+		fmt.Fprintf(c.Out, "\n===== %s PHASE: MERGED FROM %d AGENTS =====\n", strings.ToUpper(phase), len(agents))
+		for _, s := range pr.Operations {
+			stats.Print(c.Out, s)
+		}
+	}
+	return res
+}
+
+// writeJSON sends v as the response body. A failed write means the agent
+// has gone away, which its own error handling reports.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,61 @@
+// Package distributed drives one workload from several client hosts at once.
+// Agents register with a coordinator over HTTP, receive the workload, start
+// each phase on a shared barrier and stream their latency histograms back;
+// the coordinator merges them into a single report.
+package distributed
+
+import (
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// HTTP endpoints served by the coordinator.
+const (
+	pathRegister = "/v1/register"
+	pathBarrier  = "/v1/barrier"
+	pathReport   = "/v1/report"
+	pathDone     = "/v1/done"
+)
+
+// registration is sent by an agent when it connects.
+type registration struct {
+	Name string `json:"name"`
+}
+
+// Job is the work handed to each agent once every agent has registered.
+type Job struct {
+	Agent    int               `json:"agent"`
+	Agents   int               `json:"agents"`
+	Backend  backend.Config    `json:"backend"`
+	Workload workload.Workload `json:"workload"`
+	Seed     int64             `json:"seed"`
+	Retry    retry.Policy      `json:"retry"`
+	Timeout  timeout.Policy    `json:"timeout"`
+}
+
+// barrierRequest announces that an agent is ready to start phase.
+type barrierRequest struct {
+	Agent int    `json:"agent"`
+	Phase string `json:"phase"`
+}
+
+// report carries an agent's statistics for a phase so far. Final is set
+// once the phase has finished on the agent.
+type report struct {
+	Agent      int              `json:"agent"`
+	Phase      string           `json:"phase"`
+	Final      bool             `json:"final"`
+	Operations []stats.Snapshot `json:"operations"`
+}
+
+// doneRequest tells the coordinator an agent has finished, or failed, and
+// where it ran.
+type doneRequest struct {
+	Agent       int               `json:"agent"`
+	Error       string            `json:"error,omitempty"`
+	Environment *environment.Info `json:"environment,omitempty"`
+}
@@ -0,0 +1,279 @@
+// Package environment records where a benchmark ran: the host's CPU,
+// memory and kernel, the Go toolchain and SDK versions the binary was built
+// with, the backend's region and endpoint, and EC2 instance metadata when
+// the host is an EC2 instance. Results from different runs are only
+// comparable when these match.
+package environment
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+// IMDSTimeout bounds the EC2 instance metadata lookup, which hangs or fails
+// quickly everywhere but on EC2.
+const IMDSTimeout = time.Second
+
+// imdsEndpoint is the EC2 instance metadata service.
+const imdsEndpoint = "http://169.254.169.254"
+
+// sdkModules are the module path prefixes whose versions are recorded.
+var sdkModules = []string{
+	"github.com/AcceleratedCloudStorage/acs-sdk-go",
+	"github.com/aws/aws-sdk-go-v2",
+	"github.com/aws/smithy-go",
+}
+
+// Info describes the environment of one benchmark client. Fields that could
+// not be read are left empty.
+type Info struct {
+	Hostname    string `json:"hostname"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	Kernel      string `json:"kernel,omitempty"`
+	CPUModel    string `json:"cpu_model,omitempty"`
+	CPUs        int    `json:"cpus"`
+	MemoryBytes int64  `json:"memory_bytes,omitempty"`
+	GoVersion   string `json:"go_version"`
+	// Revision is the commit of this repository the binary was built from,
+	// with "-dirty" appended for uncommitted changes.
+	Revision string `json:"revision,omitempty"`
+	// Modules maps SDK module paths to the versions built in.
+	Modules map[string]string `json:"modules,omitempty"`
+	Backend *Backend          `json:"backend,omitempty"`
+	EC2     *EC2              `json:"ec2,omitempty"`
+}
+
+// Backend is where the benchmark client connects.
+type Backend struct {
+	Name     string `json:"name"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// EC2 is the instance metadata of the host.
+type EC2 struct {
+	InstanceID         string `json:"instance_id"`
+	InstanceType       string `json:"instance_type"`
+	AMIID              string `json:"ami_id,omitempty"`
+	Region             string `json:"region,omitempty"`
+	AvailabilityZone   string `json:"availability_zone,omitempty"`
+	AvailabilityZoneID string `json:"availability_zone_id,omitempty"`
+}
+
+// Capture records the environment of this process. b may be nil when no
+// backend is open, such as on a coordinator.
+func Capture(ctx context.Context, b backend.Backend) *Info {
+	info := &Info{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		info.EC2 = lookupEC2(ctx)
+	}()
+
+	info.Hostname, _ = os.Hostname()
+	if data, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		info.Kernel = strings.TrimSpace(string(data))
+	}
+	info.CPUModel = cpuModel()
+	info.MemoryBytes = memTotal()
+	info.Revision, info.Modules = buildInfo()
+	if b != nil {
+		region, endpoint := backend.Location(b, "")
+		info.Backend = &Backend{Name: b.Name(), Region: region, Endpoint: endpoint}
+	}
+
+	wg.Wait()
+	return info
+}
+
+// cpuModel returns the first model name in /proc/cpuinfo.
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// memTotal returns the MemTotal line of /proc/meminfo in bytes.
+func memTotal() int64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// MemTotal:       16314712 kB
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// buildInfo returns the VCS revision and SDK module versions embedded in
+// the binary. "go run" and "go build" embed both; "go test" does not.
+func buildInfo() (revision string, modules map[string]string) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", nil
+	}
+	modules = make(map[string]string)
+	for _, dep := range bi.Deps {
+		for _, prefix := range sdkModules {
+			if !strings.HasPrefix(dep.Path, prefix) {
+				continue
+			}
+			version := dep.Version
+			if dep.Replace != nil {
+				version += " => " + dep.Replace.Path + " " + dep.Replace.Version
+			}
+			modules[dep.Path] = strings.TrimSpace(version)
+		}
+	}
+	var dirty bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if revision != "" && dirty {
+		revision += "-dirty"
+	}
+	return revision, modules
+}
+
+// lookupEC2 reads the instance metadata with IMDSv2, falling back to IMDSv1
+// if no token is issued. It returns nil when the service is unreachable.
+func lookupEC2(ctx context.Context) *EC2 {
+	ctx, cancel := context.WithTimeout(ctx, IMDSTimeout)
+	defer cancel()
+	client := &http.Client{}
+
+	var token string
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, imdsEndpoint+"/latest/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	if resp.StatusCode == http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		token = string(data)
+	}
+	resp.Body.Close()
+
+	get := func(path string) string {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"/latest/meta-data/"+path, nil)
+		if token != "" {
+			req.Header.Set("X-aws-ec2-metadata-token", token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return ""
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return ""
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return strings.TrimSpace(string(data))
+	}
+	ec2 := &EC2{
+		InstanceID:         get("instance-id"),
+		InstanceType:       get("instance-type"),
+		AMIID:              get("ami-id"),
+		Region:             get("placement/region"),
+		AvailabilityZone:   get("placement/availability-zone"),
+		AvailabilityZoneID: get("placement/availability-zone-id"),
+	}
+	if ec2.InstanceID == "" {
+		return nil
+	}
+	return ec2
+}
+
+// displayModules are the modules Print shows; the result has them all.
+var displayModules = []string{
+	"github.com/AcceleratedCloudStorage/acs-sdk-go",
+	"github.com/aws/aws-sdk-go-v2",
+	"github.com/aws/aws-sdk-go-v2/service/s3",
+}
+
+// Print writes a short summary of the environment.
+func (info *Info) Print(out io.Writer) {
+	fmt.Fprintf(out, "Host: %s (%s/%s", info.Hostname, info.OS, info.Arch)
+	if info.Kernel != "" {
+		fmt.Fprintf(out, ", kernel %s", info.Kernel)
+	}
+	fmt.Fprintln(out, ")")
+	cpu := info.CPUModel
+	if cpu == "" {
+		cpu = "CPUs"
+	}
+	fmt.Fprintf(out, "CPU: %d x %s\n", info.CPUs, cpu)
+	if info.MemoryBytes > 0 {
+		fmt.Fprintf(out, "Memory: %.2f GB\n", float64(info.MemoryBytes)/(1024*1024*1024))
+	}
+	if e := info.EC2; e != nil {
+		fmt.Fprintf(out, "EC2: %s %s in %s", e.InstanceType, e.InstanceID, e.AvailabilityZone)
+		if e.AvailabilityZoneID != "" {
+			fmt.Fprintf(out, " (%s)", e.AvailabilityZoneID)
+		}
+		fmt.Fprintln(out)
+	}
+	build := []string{info.GoVersion}
+	for _, path := range displayModules {
+		if v, ok := info.Modules[path]; ok {
+			// github.com/aws/aws-sdk-go-v2/service/s3 -> aws-sdk-go-v2/service/s3
+			build = append(build, strings.SplitN(path, "/", 3)[2]+" "+v)
+		}
+	}
+	if info.Revision != "" {
+		build = append(build, "revision "+info.Revision)
+	}
+	fmt.Fprintf(out, "Build: %s\n", strings.Join(build, ", "))
+	if b := info.Backend; b != nil && (b.Region != "" || b.Endpoint != "") {
+		fmt.Fprintf(out, "Backend: %s", b.Name)
+		if b.Region != "" {
+			fmt.Fprintf(out, " in %s", b.Region)
+		}
+		if b.Endpoint != "" {
+			fmt.Fprintf(out, " at %s", b.Endpoint)
+		}
+		fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/metrics"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/tracing"
+)
+
+// instrumentation holds the optional observability settings shared by the
+// commands that run benchmarks.
+type instrumentation struct {
+	metricsAddr  string
+	metricsGrace time.Duration
+	tracing      tracing.Config
+	profiles     string
+	profilePhase string
+
+	exporter     *metrics.Exporter
+	flushTracing func(context.Context) error
+}
+
+// instrumentFlags registers the observability flags on fs.
+func instrumentFlags(fs *flag.FlagSet) *instrumentation {
+	in := &instrumentation{}
+	fs.StringVar(&in.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address during the run, e.g. :9100")
+	fs.DurationVar(&in.metricsGrace, "metrics-grace", 30*time.Second, "keep serving metrics this long after the run finishes")
+	fs.StringVar(&in.tracing.Endpoint, "otel-endpoint", "", "export a span per operation to this OTLP/HTTP collector, e.g. localhost:4318")
+	fs.StringVar(&in.tracing.File, "otel-file", "", "write a span per operation as JSON to this file")
+	fs.StringVar(&in.profiles, "profile", "", "capture these profiles, comma separated: "+strings.Join(profile.Kinds(), ", "))
+	fs.StringVar(&in.profilePhase, "profile-phase", profile.WholeRun, "phase to profile, or \""+profile.WholeRun+"\" for the whole run")
+	return in
+}
+
+// profilePlan returns the profiles to capture, written next to the result
+// file at out, or nil when none were requested.
+func (in *instrumentation) profilePlan(out string) (*profile.Plan, error) {
+	kinds, err := profile.ParseKinds(in.profiles)
+	if err != nil || len(kinds) == 0 {
+		return nil, err
+	}
+	dir := "."
+	if out != "" {
+		dir = filepath.Dir(out)
+	}
+	return &profile.Plan{Kinds: kinds, Phase: in.profilePhase, Dir: dir}, nil
+}
+
+// start brings up whatever the flags asked for.
+func (in *instrumentation) start(ctx context.Context) error {
+	if in.tracing.Enabled() {
+		flush, err := tracing.Setup(ctx, in.tracing)
+		if err != nil {
+			return err
+		}
+		in.flushTracing = flush
+	}
+	if in.metricsAddr != "" {
+		in.exporter = metrics.New()
+		if err := in.exporter.Serve(in.metricsAddr); err != nil {
+			return err
+		}
+		fmt.Printf("Serving metrics on http://%s/metrics\n", in.metricsAddr)
+	}
+	return nil
+}
+
+// finish flushes outstanding spans, keeps the metrics endpoint up for the
+// grace period and then shuts everything down.
+func (in *instrumentation) finish(ctx context.Context) {
+	// Flush what was collected even after a signal canceled ctx.
+	ctx = context.WithoutCancel(ctx)
+	if in.flushTracing != nil {
+		if err := in.flushTracing(ctx); err != nil {
+			fmt.Printf("Failed to flush spans: %v\n", err)
+		}
+	}
+	if in.exporter != nil {
+		fmt.Printf("\nServing final metrics for %s\n", in.metricsGrace)
+		if err := in.exporter.Shutdown(ctx, in.metricsGrace); err != nil {
+			fmt.Printf("Failed to stop metrics server: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Command bench runs object storage benchmarks against any registered backend.
+//
+// Usage:
+//
+//	bench preset list
+//	bench preset <name> --backend <backend> [flags]
+//	bench replay --trace <file> --backend <backend> [flags]
+//	bench metadata --backend <backend> [flags]
+//	bench copy --backend <backend> [flags]
+//	bench overwrite --backend <backend> [flags]
+//	bench record --target <url> [flags]
+//	bench coordinator <preset> --backend <backend> --agents <n> [flags]
+//	bench agent --coordinator <url> [flags]
+//	bench sweep --backend <backend> [--delete]
+//	bench tune <preset> --backend <backend> [flags]
+//
+// Run "bench <command> -h" for the flags of each command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	_ "github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend/acs"
+)
+
+// command is a bench subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"preset", "run a named YCSB-style workload preset", presetCmd},
+	{"replay", "replay an S3 access log or JSONL trace", replayCmd},
+	{"metadata", "measure HEAD, HeadBucket and user metadata against GET", metadataCmd},
+	{"copy", "measure server-side copy and copy-then-delete renames across sizes", copyCmd},
+	{"overwrite", "measure overwrites and conditional writes and check their semantics", overwriteCmd},
+	{"record", "record S3 traffic through a proxy into a replayable trace", recordCmd},
+	{"coordinator", "drive a preset from several agents and merge their results", coordinatorCmd},
+	{"agent", "run the workload handed out by a coordinator", agentCmd},
+	{"sweep", "find and delete buckets left behind by aborted runs", sweepCmd},
+	{"tune", "run a preset across HTTP transport settings and pick the fastest per size", tuneCmd},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "bench: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: bench <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// parseArgs parses flags that may appear before, between or after positional
+// arguments, so both "bench preset B --backend acs" and
+// "bench preset --backend acs B" work. It returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// backendFlags registers the flags that select and configure a backend.
+func backendFlags(fs *flag.FlagSet) *backend.Config {
+	cfg := &backend.Config{}
+	fs.StringVar(&cfg.Name, "backend", "", "backend to benchmark: "+strings.Join(backend.Names(), ", "))
+	fs.StringVar(&cfg.Region, "region", "", "backend region (default depends on backend)")
+	fs.StringVar(&cfg.Endpoint, "endpoint", "", "override the backend endpoint URL")
+	return cfg
+}
+
+// transportFlags registers the HTTP client flags of the S3-compatible
+// backends on fs, storing them in t.
+func transportFlags(fs *flag.FlagSet, t *backend.Transport) {
+	fs.IntVar(&t.MaxIdleConnsPerHost, "max-idle-conns-per-host", 0, "idle connections kept per host (default: the SDK's 10)")
+	fs.BoolVar(&t.DisableKeepAlives, "disable-keep-alives", false, "open a new connection for every request")
+	fs.BoolVar(&t.DisableHTTP2, "disable-http2", false, "use HTTP/1.1 even when the endpoint offers HTTP/2")
+	fs.IntVar(&t.ReadBufferSize, "read-buffer", 0, "per-connection read buffer in bytes (default: 4096)")
+	fs.IntVar(&t.WriteBufferSize, "write-buffer", 0, "per-connection write buffer in bytes (default: 4096)")
+	fs.BoolVar(&t.DisableCompression, "disable-compression", false, "do not request gzip-encoded responses")
+}
+
+// openBackend validates cfg and opens the backend it names.
+func openBackend(ctx context.Context, cfg *backend.Config) (backend.Backend, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("--backend is required (available: %s)", strings.Join(backend.Names(), ", "))
+	}
+	b, err := backend.Open(ctx, *cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backend %s: %w", cfg.Name, err)
+	}
+	return b, nil
+}
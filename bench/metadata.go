@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+)
+
+// metadataCmd implements "bench metadata".
+func metadataCmd(args []string) error {
+	fs := flag.NewFlagSet("metadata", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	inst := instrumentFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	deadline := fs.Duration("deadline", 0, "stop the run after this long and report the operations completed so far; 0 means no deadline")
+	probes := probeFlags(fs)
+	objects := fs.Int("objects", 100, "objects to write for each metadata size")
+	operations := fs.Int("operations", 1000, "calls of each kind: HEAD on an existing key, HEAD on a missing key, HEAD_BUCKET and GET")
+	size := fs.Int("size", 1024, "object size in bytes")
+	sizes := []int{0, 256, 1024}
+	fs.Func("metadata-sizes", "comma-separated user metadata sizes in bytes, at most 2048 (default 0,256,1024)", intList(&sizes))
+	threads := fs.Int("threads", 10, "concurrent workers")
+	seed := fs.Int64("seed", 1, "random seed for payloads and key choice")
+	out := fs.String("out", "", "write the structured result as JSON to this file")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench metadata --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	m := &runner.Metadata{
+		Objects:       *objects,
+		Size:          *size,
+		MetadataSizes: sizes,
+		Operations:    *operations,
+		Threads:       *threads,
+		Seed:          *seed,
+		Options: runner.Options{
+			Retry:    *retries,
+			Timeout:  *timeouts,
+			Deadline: *deadline,
+			Probe:    probes.config(),
+		},
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Running metadata benchmark on %s\n", cfg.Name)
+		usage := cost.EstimateMetadata(*objects*len(sizes), *operations, int64(*size))
+		return costs.printEstimate(os.Stdout, cfg.Name, probes.usage(usage), 1)
+	}
+	plan, err := inst.profilePlan(*out)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(runner.PhaseLoad, runner.PhaseMetadata); err != nil {
+		return err
+	}
+
+	progress.Enabled = *showProgress
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := probes.check(b); err != nil {
+		return err
+	}
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Running metadata benchmark on %s\n", b.Name())
+	fmt.Println("======================================")
+
+	if err := inst.start(ctx); err != nil {
+		return err
+	}
+	defer inst.finish(ctx)
+
+	m.Backend = b
+	m.Metrics = inst.exporter
+	m.Profile = plan
+	m.Cleanup = tracker
+	defer m.Close(ctx)
+	res, err := m.Run(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
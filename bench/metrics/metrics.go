@@ -0,0 +1,157 @@
+// Package metrics serves live benchmark counters and latency histograms on a
+// Prometheus /metrics endpoint, so long soak runs can be watched in Grafana.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+// Labels identify the series an operation is counted under.
+type Labels struct {
+	Backend  string
+	Scenario string
+	Phase    string
+}
+
+// Exporter holds the benchmark metrics and the HTTP server exposing them. A
+// nil *Exporter is valid and records nothing.
+type Exporter struct {
+	registry *prometheus.Registry
+	ops      *prometheus.CounterVec
+	bytes    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+
+	server *http.Server
+	done   chan error
+}
+
+var opLabels = []string{"backend", "scenario", "phase", "op", "size"}
+
+// New returns an Exporter with all benchmark metrics registered, along with
+// the standard Go runtime and process collectors.
+func New() *Exporter {
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		ops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bench_operations_total",
+			Help: "Completed benchmark operations, including failed ones.",
+		}, opLabels),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bench_bytes_total",
+			Help: "Object bytes transferred by successful operations.",
+		}, opLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bench_errors_total",
+			Help: "Failed benchmark operations by error class.",
+		}, []string{"backend", "scenario", "phase", "op", "size", "class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "bench_operation_duration_seconds",
+			Help: "Latency of successful benchmark operations.",
+			// 0.5ms to about 65s
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 18),
+		}, opLabels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bench_operations_in_flight",
+			Help: "Benchmark operations currently waiting on the backend.",
+		}, []string{"backend", "scenario", "phase"}),
+	}
+	e.registry.MustRegister(e.ops, e.bytes, e.errors, e.latency, e.inFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return e
+}
+
+// Serve starts serving /metrics on addr, e.g. ":9100". It returns once the
+// listener is bound, so the endpoint is scrapeable before the run starts.
+func (e *Exporter) Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{Registry: e.registry}))
+	e.server = &http.Server{Handler: mux}
+	e.done = make(chan error, 1)
+	go func() {
+		err := e.server.Serve(ln)
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		e.done <- err
+	}()
+	return nil
+}
+
+// Shutdown keeps the endpoint up for grace, so a final scrape sees the
+// complete run, and then stops the server.
+func (e *Exporter) Shutdown(ctx context.Context, grace time.Duration) error {
+	if e == nil || e.server == nil {
+		return nil
+	}
+	select {
+	case <-time.After(grace):
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return <-e.done
+}
+
+// Scope returns a recorder for operations carrying l. It returns nil when e
+// is nil.
+func (e *Exporter) Scope(l Labels) *Scope {
+	if e == nil {
+		return nil
+	}
+	return &Scope{e: e, labels: l}
+}
+
+// Scope records operations under a fixed backend, scenario and phase. A nil
+// *Scope records nothing.
+type Scope struct {
+	e      *Exporter
+	labels Labels
+}
+
+// Begin marks an operation as in flight; call the returned function when it
+// completes.
+func (s *Scope) Begin() func() {
+	if s == nil {
+		return func() {}
+	}
+	g := s.e.inFlight.WithLabelValues(s.labels.Backend, s.labels.Scenario, s.labels.Phase)
+	g.Inc()
+	return g.Dec
+}
+
+// Observe records one completed operation of the given object size.
+func (s *Scope) Observe(op string, size int64, latency time.Duration, bytes int64, err error) {
+	if s == nil {
+		return
+	}
+	values := []string{s.labels.Backend, s.labels.Scenario, s.labels.Phase, op, strconv.FormatInt(size, 10)}
+	s.e.ops.WithLabelValues(values...).Inc()
+	if err != nil {
+		s.e.errors.WithLabelValues(append(values, backend.ErrorClass(err))...).Inc()
+		return
+	}
+	s.e.bytes.WithLabelValues(values...).Add(float64(bytes))
+	s.e.latency.WithLabelValues(values...).Observe(latency.Seconds())
+}
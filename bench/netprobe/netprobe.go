@@ -0,0 +1,200 @@
+// Package netprobe measures the network path to a storage endpoint before a
+// benchmark: DNS resolution, TCP connect round trip, TLS handshake and a
+// short bulk transfer. Storage latencies are only comparable across
+// backends once the network's share of them is known.
+package netprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Defaults for Config.
+const (
+	DefaultSamples       = 5
+	DefaultTransferBytes = 8 * 1024 * 1024 // 8MB
+)
+
+// Transfers is how many times the bulk transfer is repeated; the fastest
+// counts, as the estimate is of what the path can carry.
+const Transfers = 2
+
+// Config selects what to probe.
+type Config struct {
+	// Endpoint is the URL to probe; it is usually resolved from the backend.
+	Endpoint string
+	// Samples is how many times DNS, TCP and TLS are each measured.
+	Samples int
+	// TransferBytes is the size of the object written and read back for
+	// the bandwidth estimate; zero skips it.
+	TransferBytes int
+}
+
+// Transfer moves the bandwidth probe's payload through the storage client,
+// so it takes the same path as the benchmark's own requests.
+type Transfer struct {
+	Put func(ctx context.Context, data []byte) error
+	Get func(ctx context.Context) error
+}
+
+// Result is what the probe measured.
+type Result struct {
+	Endpoint string `json:"endpoint"`
+	// Address is the IP address and port that was connected to.
+	Address      string `json:"address"`
+	DNS          Stat   `json:"dns"`
+	TCPConnect   Stat   `json:"tcp_connect"`
+	TLSHandshake *Stat  `json:"tls_handshake,omitempty"`
+	// Upload and download bandwidth in megabits per second, including the
+	// service's request overhead.
+	TransferBytes int     `json:"transfer_bytes,omitempty"`
+	UploadMbps    float64 `json:"upload_mbps,omitempty"`
+	DownloadMbps  float64 `json:"download_mbps,omitempty"`
+}
+
+// RTTMs is the network round trip time: the median TCP connect time.
+func (res *Result) RTTMs() float64 {
+	if res == nil {
+		return 0
+	}
+	return res.TCPConnect.MedianMs
+}
+
+// Stat summarises the samples of one measurement.
+type Stat struct {
+	Samples  int     `json:"samples"`
+	MinMs    float64 `json:"min_ms"`
+	MedianMs float64 `json:"median_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// Run probes cfg.Endpoint, and measures bandwidth with t if cfg asks for it.
+func Run(ctx context.Context, cfg Config, t Transfer) (*Result, error) {
+	if cfg.Samples <= 0 {
+		cfg.Samples = DefaultSamples
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+	}
+	host, port := u.Hostname(), u.Port()
+	secure := u.Scheme != "http"
+	if port == "" {
+		port = "443"
+		if !secure {
+			port = "80"
+		}
+	}
+	res := &Result{Endpoint: cfg.Endpoint}
+
+	var dns, tcp, handshake []time.Duration
+	var addr string
+	for range cfg.Samples {
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		dns = append(dns, time.Since(start))
+		addr = net.JoinHostPort(addrs[0], port)
+	}
+	res.Address = addr
+
+	var dialer net.Dialer
+	for range cfg.Samples {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		tcp = append(tcp, time.Since(start))
+		if secure {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+			start = time.Now()
+			err = tlsConn.HandshakeContext(ctx)
+			if err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("TLS handshake with %s failed: %w", host, err)
+			}
+			handshake = append(handshake, time.Since(start))
+		}
+		conn.Close()
+	}
+	res.DNS = summarize(dns)
+	res.TCPConnect = summarize(tcp)
+	if secure {
+		s := summarize(handshake)
+		res.TLSHandshake = &s
+	}
+
+	if cfg.TransferBytes > 0 && t.Put != nil && t.Get != nil {
+		data := make([]byte, cfg.TransferBytes)
+		var up, down time.Duration
+		for range Transfers {
+			start := time.Now()
+			if err := t.Put(ctx, data); err != nil {
+				return res, fmt.Errorf("bandwidth probe upload failed: %w", err)
+			}
+			up = fastest(up, time.Since(start))
+			start = time.Now()
+			if err := t.Get(ctx); err != nil {
+				return res, fmt.Errorf("bandwidth probe download failed: %w", err)
+			}
+			down = fastest(down, time.Since(start))
+		}
+		res.TransferBytes = cfg.TransferBytes
+		res.UploadMbps = mbps(cfg.TransferBytes, up)
+		res.DownloadMbps = mbps(cfg.TransferBytes, down)
+	}
+	return res, nil
+}
+
+func fastest(best, d time.Duration) time.Duration {
+	if best == 0 || d < best {
+		return d
+	}
+	return best
+}
+
+func mbps(bytes int, d time.Duration) float64 {
+	return float64(bytes) * 8 / 1e6 / d.Seconds()
+}
+
+func summarize(samples []time.Duration) Stat {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return Stat{
+		Samples:  len(sorted),
+		MinMs:    ms(sorted[0]),
+		MedianMs: ms(sorted[len(sorted)/2]),
+		MaxMs:    ms(sorted[len(sorted)-1]),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+// Print writes the measurements.
+func (res *Result) Print(out io.Writer) {
+	fmt.Fprintf(out, "\nEndpoint: %s (%s)\n", res.Endpoint, res.Address)
+	printStat(out, "DNS Resolution", res.DNS)
+	printStat(out, "TCP Connect (RTT)", res.TCPConnect)
+	if res.TLSHandshake != nil {
+		printStat(out, "TLS Handshake", *res.TLSHandshake)
+	}
+	if res.TransferBytes > 0 {
+		fmt.Fprintf(out, "Bandwidth (%.1f MB object): %.1f Mbit/s up, %.1f Mbit/s down\n",
+			float64(res.TransferBytes)/(1024*1024), res.UploadMbps, res.DownloadMbps)
+	}
+}
+
+func printStat(out io.Writer, name string, s Stat) {
+	fmt.Fprintf(out, "%s: %.2f ms median (min %.2f ms, max %.2f ms, %d samples)\n", name, s.MedianMs, s.MinMs, s.MaxMs, s.Samples)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+)
+
+// overwriteCmd implements "bench overwrite".
+func overwriteCmd(args []string) error {
+	fs := flag.NewFlagSet("overwrite", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	inst := instrumentFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	deadline := fs.Duration("deadline", 0, "stop the run after this long and report the operations completed so far; 0 means no deadline")
+	probes := probeFlags(fs)
+	size := fs.Int("size", 1024, "object size in bytes")
+	keys := fs.Int("keys", 10, "keys to spread the overwrites over")
+	operations := fs.Int("operations", 1000, "overwrites to make, and conditional writes")
+	writers := fs.Int("writers", 8, "writers racing on one key in each round")
+	rounds := fs.Int("rounds", 20, "rounds of racing writers, and overwrites read back")
+	threads := fs.Int("threads", 10, "concurrent workers outside the races")
+	seed := fs.Int64("seed", 1, "random seed for payloads and key choice")
+	out := fs.String("out", "", "write the structured result as JSON to this file")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench overwrite --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	o := &runner.Overwrite{
+		Size:       *size,
+		Keys:       *keys,
+		Operations: *operations,
+		Writers:    *writers,
+		Rounds:     *rounds,
+		Threads:    *threads,
+		Seed:       *seed,
+		Options: runner.Options{
+			Retry:    *retries,
+			Timeout:  *timeouts,
+			Deadline: *deadline,
+			Probe:    probes.config(),
+		},
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Running overwrite benchmark on %s\n", cfg.Name)
+		usage := cost.EstimateOverwrite(*keys, *operations, *writers, *rounds, *threads, int64(*size))
+		return costs.printEstimate(os.Stdout, cfg.Name, probes.usage(usage), 1)
+	}
+	plan, err := inst.profilePlan(*out)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(runner.PhaseLoad, runner.PhaseOverwrite, runner.PhaseConflict, runner.PhaseConditional); err != nil {
+		return err
+	}
+
+	progress.Enabled = *showProgress
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := probes.check(b); err != nil {
+		return err
+	}
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Running overwrite benchmark on %s\n", b.Name())
+	fmt.Println("======================================")
+
+	if err := inst.start(ctx); err != nil {
+		return err
+	}
+	defer inst.finish(ctx)
+
+	o.Backend = b
+	o.Metrics = inst.exporter
+	o.Profile = plan
+	o.Cleanup = tracker
+	defer o.Close(ctx)
+	res, err := o.Run(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/payload"
+)
+
+// payloadFlag registers --payload, which sets the data written to objects.
+// If many is set it takes a comma-separated list, assigned to records in
+// turn and reported per payload.
+func payloadFlag(fs *flag.FlagSet, many bool) *[]*payload.Generator {
+	var gens []*payload.Generator
+	usage := "data written to objects: " + strings.Join(payload.Kinds(), ", ") + " (default \"" + payload.Default + "\")"
+	if many {
+		usage = "comma-separated " + usage + "; several are spread over the records and reported separately"
+	}
+	fs.Func("payload", usage, func(s string) error {
+		var err error
+		gens, err = payload.Parse(s)
+		if err == nil && !many && len(gens) > 1 {
+			err = errors.New("only one payload may be given")
+		}
+		return err
+	})
+	return &gens
+}
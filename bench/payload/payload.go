@@ -0,0 +1,242 @@
+// Package payload generates the object data benchmarks write, at a chosen
+// compressibility, so clients that compress on the wire can be compared on
+// data like the data they will really carry.
+package payload
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of payload.
+const (
+	// KindRandom is incompressible random data.
+	KindRandom = "random"
+	// KindZeros is all zero bytes, the most compressible data there is.
+	KindZeros = "zeros"
+	// KindText is English-like prose.
+	KindText = "text"
+	// KindJSON is newline-delimited JSON log records.
+	KindJSON = "json"
+	// KindRatio mixes random and zero bytes to reach a target ratio,
+	// written "ratio:4".
+	KindRatio = "ratio"
+	// KindCorpus samples a local file, written "corpus:/path/to/file".
+	KindCorpus = "corpus"
+)
+
+// Default is the payload used when none is chosen.
+const Default = KindRandom
+
+// poolSize is how much text, JSON or mixed data is generated up front.
+// Objects copy from it at random offsets; it is larger than the windows of
+// LZ4 and DEFLATE, so an object never compresses better for repeating it.
+const poolSize = 4 << 20
+
+// sampleSize is how much data the compression ratio is measured on.
+const sampleSize = 1 << 20
+
+// Kinds returns the names of the payload kinds.
+func Kinds() []string {
+	return []string{KindRandom, KindZeros, KindText, KindJSON, KindRatio + ":<n>", KindCorpus + ":<file>"}
+}
+
+// Generator fills object buffers with one kind of payload. It is safe for
+// concurrent use as long as each goroutine passes its own rand.Rand.
+type Generator struct {
+	name  string
+	kind  string
+	pool  []byte
+	ratio float64
+}
+
+// Info describes a payload in results.
+type Info struct {
+	Name string `json:"name"`
+	// Ratio is the payload's DEFLATE compression ratio at the fastest
+	// level, e.g. 3.2 for data that shrinks to under a third.
+	Ratio float64 `json:"deflate_ratio"`
+}
+
+// New returns the generator for spec, such as "json", "ratio:4" or
+// "corpus:/data/sample.parquet".
+func New(spec string) (*Generator, error) {
+	spec = strings.TrimSpace(spec)
+	kind, arg, _ := strings.Cut(spec, ":")
+	g := &Generator{name: spec, kind: kind}
+	rng := rand.New(rand.NewSource(1))
+	switch kind {
+	case KindRandom, KindZeros:
+	case KindText:
+		g.pool = text(rng, poolSize)
+	case KindJSON:
+		g.pool = jsonLines(rng, poolSize)
+	case KindRatio:
+		ratio, err := strconv.ParseFloat(arg, 64)
+		if err != nil || ratio < 1 {
+			return nil, fmt.Errorf("invalid payload %q: want ratio:<n> with n of at least 1", spec)
+		}
+		g.pool = mixed(rng, poolSize, ratio)
+	case KindCorpus:
+		if arg == "" {
+			return nil, fmt.Errorf("invalid payload %q: want corpus:<file>", spec)
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload corpus: %w", err)
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("payload corpus %s is empty", arg)
+		}
+		g.pool = data
+	default:
+		return nil, fmt.Errorf("unknown payload %q (available: %s)", spec, strings.Join(Kinds(), ", "))
+	}
+	sample := make([]byte, sampleSize)
+	g.Fill(rng, sample)
+	g.ratio = Ratio(sample)
+	return g, nil
+}
+
+// Parse returns the generators for a comma-separated list of specs.
+func Parse(specs string) ([]*Generator, error) {
+	var gens []*Generator
+	for _, spec := range strings.Split(specs, ",") {
+		g, err := New(spec)
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, g)
+	}
+	return gens, nil
+}
+
+// Name returns the spec the generator was made from.
+func (g *Generator) Name() string { return g.name }
+
+// Info describes the generator.
+func (g *Generator) Info() Info {
+	return Info{Name: g.name, Ratio: g.ratio}
+}
+
+// String describes g, e.g. "json (deflate ratio 5.21:1)".
+func (g *Generator) String() string {
+	return fmt.Sprintf("%s (deflate ratio %.2f:1)", g.name, g.ratio)
+}
+
+// Fill overwrites buf with fresh payload drawn with rng.
+func (g *Generator) Fill(rng *rand.Rand, buf []byte) {
+	switch {
+	case g.kind == KindZeros:
+		clear(buf)
+	case g.pool == nil:
+		rng.Read(buf)
+	default:
+		off := rng.Intn(len(g.pool))
+		for n := 0; n < len(buf); {
+			n += copy(buf[n:], g.pool[off:])
+			off = 0
+		}
+	}
+}
+
+// Ratio returns how many times smaller DEFLATE at its fastest level makes
+// data.
+func Ratio(data []byte) float64 {
+	if len(data) == 0 {
+		return 1
+	}
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	w.Write(data)
+	w.Close()
+	return float64(len(data)) / float64(buf.Len())
+}
+
+// words is the vocabulary of generated text: common English words.
+var words = strings.Fields(`the of and to a in is it that was for on are with as
+	be at by this have from or one had not but what all were when we there can
+	an your which their said if do will each about how up out them then she many
+	some so these would other into has more her two like him see time could no
+	make than first been its who now people my made over did down only way find
+	use may water long little very after words called just where most know get
+	through back much before go good new write our used me man too any day same
+	right look think also around another came come work three word must because
+	does part even place well such here take why things help put years different
+	away again off went old number great tell men say small every found still
+	between name should home big give air line set own under read last never us
+	left end along while might next sound below saw something thought both few
+	those always looked show large often together asked house world going want
+	school important until form food keep children feet land side without boy
+	once animals life enough took sometimes four head above kind began almost
+	live page got earth need far hand high year mother light parts country
+	father let night following picture being study second eyes soon times story
+	boys since white days ever paper hard near sentence better best across
+	during today others however sure means knew its try told young miles sun
+	ways thing whole hear example heard several change answer room sea against
+	top turned learn point city play toward five using himself usually money
+	seen car morning given body family later turn move face door cut done group
+	true half red fish plants living black eat short united run book gave order
+	open ground cold really table remember tree course front american space
+	inside ago making`)
+
+// text returns n bytes of English-like prose.
+func text(rng *rand.Rand, n int) []byte {
+	var b bytes.Buffer
+	for b.Len() < n {
+		sentence := 5 + rng.Intn(15)
+		for i := range sentence {
+			w := words[rng.Intn(len(words))]
+			if i == 0 {
+				w = strings.ToUpper(w[:1]) + w[1:]
+			}
+			b.WriteString(w)
+			if i < sentence-1 {
+				b.WriteByte(' ')
+			}
+		}
+		if rng.Intn(5) == 0 {
+			b.WriteString(".\n")
+		} else {
+			b.WriteString(". ")
+		}
+	}
+	return b.Bytes()[:n]
+}
+
+// jsonLines returns n bytes of newline-delimited JSON log records.
+func jsonLines(rng *rand.Rand, n int) []byte {
+	levels := []string{"debug", "info", "info", "info", "warn", "error"}
+	services := []string{"api", "auth", "billing", "search", "ingest", "worker"}
+	methods := []string{"GET", "GET", "GET", "POST", "PUT", "DELETE"}
+	statuses := []int{200, 200, 200, 200, 201, 204, 304, 400, 404, 500}
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b bytes.Buffer
+	for b.Len() < n {
+		ts = ts.Add(time.Duration(rng.Intn(2000)) * time.Millisecond)
+		msg := text(rng, 20+rng.Intn(60))
+		fmt.Fprintf(&b, `{"ts":%q,"level":%q,"service":%q,"request_id":"%016x","user_id":%d,"method":%q,"path":"/v1/items/%d","status":%d,"latency_ms":%.2f,"bytes":%d,"msg":%q}`+"\n",
+			ts.Format(time.RFC3339Nano), levels[rng.Intn(len(levels))], services[rng.Intn(len(services))],
+			rng.Uint64(), rng.Intn(100000), methods[rng.Intn(len(methods))], rng.Intn(1000000),
+			statuses[rng.Intn(len(statuses))], rng.ExpFloat64()*20, rng.Intn(1<<20), msg)
+	}
+	return b.Bytes()[:n]
+}
+
+// mixed returns n bytes that compress by about ratio: each block starts
+// with random bytes and is padded with zeros.
+func mixed(rng *rand.Rand, n int, ratio float64) []byte {
+	const block = 256
+	b := make([]byte, n)
+	random := max(1, int(block/ratio))
+	for off := 0; off < n; off += block {
+		rng.Read(b[off:min(n, off+random)])
+	}
+	return b
+}
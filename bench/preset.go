@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// presetCmd implements "bench preset".
+func presetCmd(args []string) error {
+	fs := flag.NewFlagSet("preset", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	inst := instrumentFlags(fs)
+	wf := workloadFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	payloads := payloadFlag(fs, true)
+	deadline := fs.Duration("deadline", 0, "stop the run after this long and report the operations completed so far; 0 means no deadline")
+	probes := probeFlags(fs)
+	cold := fs.Bool("cold", false, "open a fresh client for every run-phase operation and break down its latency")
+	out := fs.String("out", "", "write the structured result as JSON to this file")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench preset list")
+		fmt.Fprintln(fs.Output(), "       bench preset <name> --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if positional[0] == "list" {
+		listPresets()
+		return nil
+	}
+
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	w, err := wf.workload(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Workload %s (%s) on %s\n", w.Name, w.Title, cfg.Name)
+		return costs.printEstimate(os.Stdout, cfg.Name, probes.usage(cost.EstimateWorkload(w)), 1)
+	}
+	plan, err := inst.profilePlan(*out)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(runner.PhaseLoad, runner.PhaseRun); err != nil {
+		return err
+	}
+
+	progress.Enabled = *showProgress
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := probes.check(b); err != nil {
+		return err
+	}
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Workload %s (%s) on %s\n", w.Name, w.Title, b.Name())
+	fmt.Println("======================================")
+
+	if err := inst.start(ctx); err != nil {
+		return err
+	}
+	defer inst.finish(ctx)
+
+	r := &runner.Runner{
+		Backend:  b,
+		Workload: w,
+		Seed:     *wf.seed,
+		Payloads: *payloads,
+		Options: runner.Options{
+			Retry:    *retries,
+			Timeout:  *timeouts,
+			Deadline: *deadline,
+			Metrics:  inst.exporter,
+			Profile:  plan,
+			Cleanup:  tracker,
+			Probe:    probes.config(),
+		},
+	}
+	if *cold {
+		r.NewClient = coldClients(*cfg)
+	}
+	defer r.Close(ctx)
+	res, err := r.Run(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
+
+// coldClients returns a function that opens a fresh backend client from cfg.
+// Each client makes a single request, so keep-alive is turned off and its
+// connection is closed afterwards instead of lingering in an idle pool.
+func coldClients(cfg backend.Config) func(ctx context.Context) (backend.Backend, error) {
+	cfg.Transport.DisableKeepAlives = true
+	return func(ctx context.Context) (backend.Backend, error) {
+		return backend.Open(ctx, cfg)
+	}
+}
+
+// presetFlags holds the flags that size and shape a preset workload.
+type presetFlags struct {
+	records, operations, size, threads *int
+	dist                               *string
+	theta, hotKeys, hotOps             *float64
+	seed                               *int64
+}
+
+// workloadFlags registers the flags shared by every command that runs a
+// preset.
+func workloadFlags(fs *flag.FlagSet) *presetFlags {
+	return &presetFlags{
+		records:    fs.Int("records", workload.DefaultRecordCount, "objects written in the load phase"),
+		operations: fs.Int("operations", workload.DefaultOperationCount, "operations issued in the run phase"),
+		size:       fs.Int("size", workload.DefaultObjectSize, "object size in bytes"),
+		threads:    fs.Int("threads", workload.DefaultThreads, "concurrent workers"),
+		dist:       fs.String("distribution", "", "override the preset's key distribution: "+strings.Join(workload.Distributions(), ", ")),
+		theta:      fs.Float64("zipf-theta", workload.DefaultZipfTheta, "skew of the zipfian and latest distributions, in (0, 1)"),
+		hotKeys:    fs.Float64("hot-keys", workload.DefaultHotKeys, "fraction of records that are hot in the hotspot distribution"),
+		hotOps:     fs.Float64("hot-ops", workload.DefaultHotOps, "fraction of operations sent to hot records in the hotspot distribution"),
+		seed:       fs.Int64("seed", 1, "random seed for key choice and payloads"),
+	}
+}
+
+// workload returns the named preset with the flags applied.
+func (f *presetFlags) workload(name string) (workload.Workload, error) {
+	w, err := workload.Preset(name)
+	if err != nil {
+		return w, err
+	}
+	w.RecordCount = *f.records
+	w.OperationCount = *f.operations
+	w.ObjectSize = *f.size
+	w.Threads = *f.threads
+	if *f.dist != "" {
+		w.Distribution = *f.dist
+	}
+	switch w.Distribution {
+	case workload.DistZipfian, workload.DistLatest:
+		w.ZipfTheta = *f.theta
+	case workload.DistHotspot:
+		w.HotKeys = *f.hotKeys
+		w.HotOps = *f.hotOps
+	}
+	return w, w.Validate()
+}
+
+// listPresets prints every preset with its description.
+func listPresets() {
+	for _, name := range workload.PresetNames() {
+		w, _ := workload.Preset(name)
+		fmt.Fprintf(os.Stdout, "%s  %s\n    %s\n", w.Name, w.Title, w.Description)
+	}
+}
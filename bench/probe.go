@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/netprobe"
+)
+
+// probeOptions holds the flags of the network probe run before a benchmark.
+type probeOptions struct {
+	enabled bool
+	cfg     netprobe.Config
+}
+
+// probeFlags registers the network probe flags on fs.
+func probeFlags(fs *flag.FlagSet) *probeOptions {
+	o := &probeOptions{}
+	fs.BoolVar(&o.enabled, "probe", true, "measure DNS, TCP, TLS and bandwidth to the backend's endpoint before the run")
+	fs.StringVar(&o.cfg.Endpoint, "probe-endpoint", "", "URL to probe (default: the endpoint the backend's client uses)")
+	fs.IntVar(&o.cfg.Samples, "probe-samples", netprobe.DefaultSamples, "DNS, TCP and TLS measurements to take")
+	fs.IntVar(&o.cfg.TransferBytes, "probe-bytes", netprobe.DefaultTransferBytes, "size of the object written and read back to estimate bandwidth; 0 skips it")
+	return o
+}
+
+// config returns the probe to run, or nil if it is turned off.
+func (o *probeOptions) config() *netprobe.Config {
+	if !o.enabled {
+		return nil
+	}
+	return &o.cfg
+}
+
+// check rejects a probe that has nowhere to connect: a backend that reports
+// its location but not its endpoint, such as ACS, whose SDK picks the
+// endpoint itself, needs --probe-endpoint. Backends without a location, such
+// as fake, have no network path to probe and skip it.
+func (o *probeOptions) check(b backend.Backend) error {
+	if !o.enabled || o.cfg.Endpoint != "" {
+		return nil
+	}
+	if _, ok := b.(backend.Locator); !ok {
+		return nil
+	}
+	if _, endpoint := backend.Location(b, ""); endpoint == "" {
+		return fmt.Errorf("backend %s does not report its endpoint; pass --probe-endpoint <url> or --probe=false", b.Name())
+	}
+	return nil
+}
+
+// usage adds the requests of the bandwidth probe to a cost estimate.
+func (o *probeOptions) usage(u cost.Usage) cost.Usage {
+	if o.enabled && o.cfg.TransferBytes > 0 {
+		u.AddProbe(netprobe.Transfers, int64(o.cfg.TransferBytes))
+	}
+	return u
+}
@@ -0,0 +1,192 @@
+// Package profile captures Go CPU, heap, mutex and block profiles and a
+// runtime execution trace around a benchmark phase, so client-side hotspots
+// in the SDKs can be investigated after the run.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strings"
+)
+
+// Capture kinds.
+const (
+	CPU   = "cpu"
+	Heap  = "heap"
+	Mutex = "mutex"
+	Block = "block"
+	Trace = "trace"
+)
+
+// WholeRun is the phase name that captures the entire run.
+const WholeRun = "all"
+
+// Kinds returns every capture kind.
+func Kinds() []string {
+	return []string{CPU, Heap, Mutex, Block, Trace}
+}
+
+// ParseKinds parses a comma-separated list of capture kinds.
+func ParseKinds(s string) ([]string, error) {
+	var kinds []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		if !slices.Contains(Kinds(), k) {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", k, strings.Join(Kinds(), ", "))
+		}
+		if !slices.Contains(kinds, k) {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds, nil
+}
+
+// Plan says what to capture and when. A nil *Plan captures nothing.
+type Plan struct {
+	Kinds []string
+	// Phase is the phase to capture, or WholeRun.
+	Phase string
+	// Dir receives the capture files.
+	Dir string
+}
+
+// Check returns an error unless the plan's phase is WholeRun or one of
+// phases.
+func (p *Plan) Check(phases ...string) error {
+	if p == nil || p.Phase == WholeRun || slices.Contains(phases, p.Phase) {
+		return nil
+	}
+	return fmt.Errorf("cannot profile phase %q: this run has phases %s and %s", p.Phase, strings.Join(phases, ", "), WholeRun)
+}
+
+// Start begins capturing if phase is the planned one. Files are named
+// "<name>-<phase>.<kind>", e.g. "preset-a-s3-run.cpu.pprof". It returns nil
+// when there is nothing to capture.
+func (p *Plan) Start(name, phase string) (*Capture, error) {
+	if p == nil || len(p.Kinds) == 0 || p.Phase != phase {
+		return nil, nil
+	}
+	c := &Capture{base: filepath.Join(p.Dir, name+"-"+phase), kinds: p.Kinds}
+	if err := c.start(); err != nil {
+		c.abort()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Capture is a profiling session in progress.
+type Capture struct {
+	base  string
+	kinds []string
+	cpu   *os.File
+	trace *os.File
+	files []string
+}
+
+func (c *Capture) path(kind string) string {
+	if kind == Trace {
+		return c.base + ".trace.out"
+	}
+	return c.base + "." + kind + ".pprof"
+}
+
+func (c *Capture) start() error {
+	for _, kind := range c.kinds {
+		switch kind {
+		case CPU:
+			f, err := os.Create(c.path(CPU))
+			if err != nil {
+				return err
+			}
+			if err := pprof.StartCPUProfile(f); err != nil {
+				f.Close()
+				return fmt.Errorf("failed to start CPU profile: %w", err)
+			}
+			c.cpu = f
+		case Trace:
+			f, err := os.Create(c.path(Trace))
+			if err != nil {
+				return err
+			}
+			if err := trace.Start(f); err != nil {
+				f.Close()
+				return fmt.Errorf("failed to start execution trace: %w", err)
+			}
+			c.trace = f
+		case Mutex:
+			runtime.SetMutexProfileFraction(1)
+		case Block:
+			runtime.SetBlockProfileRate(1)
+		}
+	}
+	return nil
+}
+
+// Stop ends the capture, writes the snapshot profiles and returns the paths
+// of every file written. A nil *Capture writes nothing.
+func (c *Capture) Stop() ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var errs []error
+	if c.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, c.close(c.cpu, CPU))
+	}
+	if c.trace != nil {
+		trace.Stop()
+		errs = append(errs, c.close(c.trace, Trace))
+	}
+	for _, kind := range c.kinds {
+		switch kind {
+		case Heap:
+			runtime.GC() // up-to-date in-use figures
+			errs = append(errs, c.snapshot(Heap))
+		case Mutex:
+			errs = append(errs, c.snapshot(Mutex))
+			runtime.SetMutexProfileFraction(0)
+		case Block:
+			errs = append(errs, c.snapshot(Block))
+			runtime.SetBlockProfileRate(0)
+		}
+	}
+	return c.files, errors.Join(errs...)
+}
+
+// abort stops a capture that failed to start, without writing snapshots.
+func (c *Capture) abort() {
+	c.kinds = nil
+	c.Stop()
+	runtime.SetMutexProfileFraction(0)
+	runtime.SetBlockProfileRate(0)
+}
+
+func (c *Capture) close(f *os.File, kind string) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.files = append(c.files, c.path(kind))
+	return nil
+}
+
+// snapshot writes the runtime profile of the given kind.
+func (c *Capture) snapshot(kind string) error {
+	f, err := os.Create(c.path(kind))
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(kind).WriteTo(f, 0); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s profile: %w", kind, err)
+	}
+	return c.close(f, kind)
+}
@@ -0,0 +1,285 @@
+// Package progress shows a live status line for long benchmark phases: ops
+// completed and remaining, current throughput, rolling p50/p99, errors and
+// ETA. A single large transfer is tracked by bytes instead. When stdout is
+// not a terminal it prints periodic log lines instead, so CI logs stay
+// readable.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// liveInterval is the redraw period on a terminal.
+	liveInterval = 250 * time.Millisecond
+	// logInterval is the period of log lines when stdout is not a terminal.
+	logInterval = 10 * time.Second
+	// window is how far back throughput and percentiles look.
+	window = 10 * time.Second
+	// maxSamples caps the samples kept for the rolling percentiles.
+	maxSamples = 10000
+)
+
+// Enabled turns progress reporting on or off for every Tracker started
+// afterwards. Commands clear it for --progress=false.
+var Enabled = true
+
+// Tracker reports the progress of one phase. It is safe for concurrent use.
+type Tracker struct {
+	name  string
+	total int
+	out   io.Writer
+	live  bool
+	start time.Time
+
+	// size is the byte count of a transfer tracked with StartBytes, and
+	// watch, when set, reads how many of its bytes have moved.
+	size  int64
+	watch func() int64
+
+	mu      sync.Mutex
+	done    int
+	moved   int64
+	errors  int
+	samples []sample // ring buffer of recent completions
+	next    int      // ring position to overwrite once samples is full
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	bytes   int64
+}
+
+// Start begins tracking a phase of total operations, drawing to stdout.
+func Start(name string, total int) *Tracker {
+	return StartWriter(os.Stdout, name, total)
+}
+
+// StartWriter is like Start but draws to out. Live redraws are used only
+// when out is a terminal.
+func StartWriter(out io.Writer, name string, total int) *Tracker {
+	return start(&Tracker{name: name, total: total, out: out})
+}
+
+func start(t *Tracker) *Tracker {
+	t.live = isTerminal(t.out)
+	t.start = time.Now()
+	t.stop = make(chan struct{})
+	t.stopped = make(chan struct{})
+	if !Enabled {
+		close(t.stopped)
+		return t
+	}
+	go t.loop()
+	return t
+}
+
+// StartBytes begins tracking a single transfer of size bytes, drawing to
+// stdout. Count the bytes with Reader or Writer, or with Watch when the
+// client takes and returns whole buffers.
+func StartBytes(name string, size int64) *Tracker {
+	return start(&Tracker{name: name, total: 1, size: size, out: os.Stdout})
+}
+
+// Watch makes the tracker read the bytes moved so far from count on every
+// redraw, for transfers that cannot be wrapped, e.g. with a network counter.
+// Call it right after StartBytes.
+func (t *Tracker) Watch(count func() int64) {
+	t.mu.Lock()
+	t.watch = count
+	t.mu.Unlock()
+}
+
+// Add records n more bytes of the transfer.
+func (t *Tracker) Add(n int64) {
+	t.mu.Lock()
+	t.moved += n
+	t.mu.Unlock()
+}
+
+// Reader returns r counting the bytes read through it.
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, t: t}
+}
+
+// Writer returns w counting the bytes written through it.
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	return &countingWriter{w: w, t: t}
+}
+
+type countingReader struct {
+	r io.Reader
+	t *Tracker
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.t.Add(int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	t *Tracker
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.t.Add(int64(n))
+	return n, err
+}
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Observe records one completed operation.
+func (t *Tracker) Observe(latency time.Duration, bytes int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done++
+	if err != nil {
+		t.errors++
+		return
+	}
+	s := sample{at: time.Now(), latency: latency, bytes: bytes}
+	if len(t.samples) < maxSamples {
+		t.samples = append(t.samples, s)
+	} else {
+		t.samples[t.next] = s
+		t.next = (t.next + 1) % maxSamples
+	}
+}
+
+// Finish stops the display. On a terminal the status line is cleared; in log
+// mode a final line is printed.
+func (t *Tracker) Finish() {
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	<-t.stopped
+}
+
+func (t *Tracker) loop() {
+	defer close(t.stopped)
+	interval := logInterval
+	if t.live {
+		interval = liveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.render(false)
+		case <-t.stop:
+			t.render(true)
+			return
+		}
+	}
+}
+
+func (t *Tracker) render(final bool) {
+	line := t.status()
+	switch {
+	case t.live && final:
+		fmt.Fprint(t.out, "\r\033[2K")
+	case t.live:
+		fmt.Fprintf(t.out, "\r\033[2K%s", line)
+	default:
+		fmt.Fprintln(t.out, line)
+	}
+}
+
+// status formats the current progress as one line.
+func (t *Tracker) status() string {
+	if t.size > 0 {
+		return t.bytesStatus()
+	}
+	t.mu.Lock()
+	now := time.Now()
+	var recent []time.Duration
+	var recentBytes int64
+	for _, s := range t.samples {
+		if now.Sub(s.at) <= window {
+			recent = append(recent, s.latency)
+			recentBytes += s.bytes
+		}
+	}
+	done, errors, total := t.done, t.errors, t.total
+	t.mu.Unlock()
+
+	elapsed := now.Sub(t.start)
+	span := window
+	if elapsed < span {
+		span = elapsed
+	}
+	var opsPerSec, mbPerSec float64
+	if span > 0 {
+		opsPerSec = float64(len(recent)) / span.Seconds()
+		mbPerSec = float64(recentBytes) / span.Seconds() / (1024 * 1024)
+	}
+
+	line := fmt.Sprintf("[%s] %d/%d ops (%d left) | %.2f ops/sec %.2f MB/sec",
+		t.name, done, total, total-done, opsPerSec, mbPerSec)
+	if len(recent) > 0 {
+		sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+		line += fmt.Sprintf(" | p50 %.2f ms p99 %.2f ms", ms(recent[len(recent)/2]), ms(recent[len(recent)*99/100]))
+	}
+	line += fmt.Sprintf(" | errors %d | elapsed %s", errors, elapsed.Round(time.Second))
+	if remaining := total - done; remaining > 0 && opsPerSec > 0 {
+		eta := time.Duration(float64(remaining) / opsPerSec * float64(time.Second))
+		line += fmt.Sprintf(" | ETA %s", eta.Round(time.Second))
+	}
+	return line
+}
+
+// bytesStatus formats the progress of a transfer tracked with StartBytes.
+// Its rate is the average since the start, as there is only one operation.
+func (t *Tracker) bytesStatus() string {
+	t.mu.Lock()
+	moved, errors, watch := t.moved, t.errors, t.watch
+	t.mu.Unlock()
+	if watch != nil {
+		moved = watch()
+	}
+	moved = min(moved, t.size)
+
+	elapsed := time.Since(t.start)
+	var mbPerSec float64
+	if elapsed > 0 {
+		mbPerSec = float64(moved) / elapsed.Seconds() / (1024 * 1024)
+	}
+	line := fmt.Sprintf("[%s] %.1f/%.1f MB (%.0f%%) | %.2f MB/sec | errors %d | elapsed %s",
+		t.name, float64(moved)/(1024*1024), float64(t.size)/(1024*1024), 100*float64(moved)/float64(t.size),
+		mbPerSec, errors, elapsed.Round(time.Second))
+	if remaining := t.size - moved; remaining > 0 && moved > 0 {
+		eta := time.Duration(float64(remaining) / float64(moved) * float64(elapsed))
+		line += fmt.Sprintf(" | ETA %s", eta.Round(time.Second))
+	}
+	return line
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
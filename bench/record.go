@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// recordCmd implements "bench record".
+func recordCmd(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	target := fs.String("target", "", "S3-compatible endpoint to forward to, e.g. https://fly.storage.tigris.dev (required)")
+	listen := fs.String("listen", "127.0.0.1:8080", "address the proxy listens on")
+	path := fs.String("trace", "trace.jsonl", "JSONL trace file to write")
+	region := fs.String("region", "us-east-1", "signing region of the target; use auto for Tigris")
+	sign := fs.Bool("sign", true, "re-sign requests for the target with the default AWS credentials")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench record --target <url> [flags]")
+		fmt.Fprintln(fs.Output(), "\nPoint clients at the proxy with BaseEndpoint set to http://<listen> and")
+		fmt.Fprintln(fs.Output(), "UsePathStyle enabled. Stop with Ctrl-C; the trace can be replayed with")
+		fmt.Fprintln(fs.Output(), "bench replay --trace <file>.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *target == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		return fmt.Errorf("invalid target URL %q", *target)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := trace.RecorderConfig{Target: targetURL, Region: *region}
+	if *sign {
+		awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(*region))
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		cfg.Credentials = awsCfg.Credentials
+	}
+
+	f, err := os.Create(*path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %w", err)
+	}
+	defer f.Close()
+
+	rec := trace.NewRecorder(cfg, f)
+	server := &http.Server{Addr: *listen, Handler: rec}
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	fmt.Printf("Recording requests to %s on http://%s into %s\n", targetURL, *listen, *path)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	fmt.Printf("\nRecorded %d operations to %s\n", rec.Count(), *path)
+	return f.Close()
+}
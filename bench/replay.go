@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/payload"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// replayCmd implements "bench replay".
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	transportFlags(fs, &cfg.Transport)
+	inst := instrumentFlags(fs)
+	costs := pricingFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	payloads := payloadFlag(fs, false)
+	deadline := fs.Duration("deadline", 0, "stop the run after this long and report the operations completed so far; 0 means no deadline")
+	probes := probeFlags(fs)
+	path := fs.String("trace", "", "trace file to replay (required)")
+	format := fs.String("format", trace.FormatAuto, "trace format: auto, jsonl or s3log")
+	speed := fs.Float64("speed", 1, "replay speed: 1 keeps the original timing, 10 is ten times faster, 0 is as fast as possible")
+	concurrency := fs.Int("concurrency", 64, "maximum operations in flight")
+	replayFailed := fs.Bool("replay-failed", false, "also replay records whose logged status is not 2xx, as if they had succeeded")
+	seed := fs.Int64("seed", 1, "random seed for synthetic payloads")
+	out := fs.String("out", "", "write the structured result as JSON to this file")
+	showProgress := fs.Bool("progress", true, "show live progress; log lines when stdout is not a terminal")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench replay --trace <file> --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *path == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	records, err := trace.ReadFile(*path, *format)
+	if err != nil {
+		return fmt.Errorf("failed to read trace: %w", err)
+	}
+	failed := 0
+	if !*replayFailed {
+		records, failed = trace.Successful(records)
+		if failed > 0 {
+			fmt.Printf("Leaving out %d records that failed when they were logged\n", failed)
+		}
+	}
+	if err := costs.load(cfg.Name); err != nil {
+		return err
+	}
+	if *costs.estimate {
+		fmt.Printf("Replaying trace %s on %s\n", *path, cfg.Name)
+		return costs.printEstimate(os.Stdout, cfg.Name, probes.usage(cost.EstimateTrace(records, *speed)), 1)
+	}
+	plan, err := inst.profilePlan(*out)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(runner.PhasePrepare, runner.PhaseReplay); err != nil {
+		return err
+	}
+
+	progress.Enabled = *showProgress
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := probes.check(b); err != nil {
+		return err
+	}
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Replaying trace %s on %s\n", *path, b.Name())
+	fmt.Println("======================================")
+
+	if err := inst.start(ctx); err != nil {
+		return err
+	}
+	defer inst.finish(ctx)
+
+	var gen *payload.Generator
+	if len(*payloads) > 0 {
+		gen = (*payloads)[0]
+	}
+	rp := &runner.Replay{
+		Backend:     b,
+		Records:     records,
+		Source:      *path,
+		Failed:      failed,
+		Speed:       *speed,
+		Concurrency: *concurrency,
+		Seed:        *seed,
+		Payload:     gen,
+		Options: runner.Options{
+			Retry:    *retries,
+			Timeout:  *timeouts,
+			Deadline: *deadline,
+			Metrics:  inst.exporter,
+			Profile:  plan,
+			Cleanup:  tracker,
+			Probe:    probes.config(),
+		},
+	}
+	defer rp.Close(ctx)
+	res, err := rp.Run(ctx)
+	if err != nil {
+		return err
+	}
+	costs.report(os.Stdout, res)
+	if *out != "" {
+		if err := res.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nResult written to %s\n", *out)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Package resources samples the benchmark client's own resource usage, CPU
+// time, memory, goroutines, garbage collection and network traffic, so SDKs
+// can be compared on client cost as well as latency.
+package resources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Interval is how often peak RSS and goroutine counts are sampled.
+const Interval = 100 * time.Millisecond
+
+// Usage is the client resource usage over one phase. Network counters come
+// from /proc/net/dev and cover every process in the network namespace; they
+// are zero where /proc is unavailable.
+type Usage struct {
+	CPUSeconds       float64 `json:"cpu_seconds"`
+	CPUUserSeconds   float64 `json:"cpu_user_seconds"`
+	CPUSystemSeconds float64 `json:"cpu_system_seconds"`
+	PeakRSSBytes     int64   `json:"peak_rss_bytes"`
+	PeakGoroutines   int     `json:"peak_goroutines"`
+	GCCycles         uint32  `json:"gc_cycles"`
+	GCPauseTotalMs   float64 `json:"gc_pause_total_ms"`
+	GCPauseMaxMs     float64 `json:"gc_pause_max_ms"`
+	AllocBytes       uint64  `json:"alloc_bytes"`
+	NetRxBytes       int64   `json:"net_rx_bytes"`
+	NetTxBytes       int64   `json:"net_tx_bytes"`
+
+	// Efficiency relative to the work done in the phase; zero when the
+	// phase moved no data or completed no operations.
+	CPUSecondsPerGB    float64 `json:"cpu_seconds_per_gb"`
+	AllocBytesPerOp    float64 `json:"alloc_bytes_per_op"`
+	NetBytesPerPayload float64 `json:"net_bytes_per_payload_byte"`
+}
+
+// counters are the cumulative readings a phase's usage is the difference of.
+type counters struct {
+	user, system time.Duration
+	mem          runtime.MemStats
+	rx, tx       int64
+}
+
+func read() counters {
+	var c counters
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err == nil {
+		c.user = time.Duration(ru.Utime.Nano())
+		c.system = time.Duration(ru.Stime.Nano())
+	}
+	runtime.ReadMemStats(&c.mem)
+	c.rx, c.tx = netBytes()
+	return c
+}
+
+// Sampler measures resource usage between Start and Stop.
+type Sampler struct {
+	begin counters
+
+	mu             sync.Mutex
+	peakRSS        int64
+	peakGoroutines int
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Start begins sampling.
+func Start() *Sampler {
+	s := &Sampler{
+		begin:   read(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	s.sample()
+	go s.loop()
+	return s
+}
+
+func (s *Sampler) loop() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sample updates the peaks of the point-in-time readings.
+func (s *Sampler) sample() {
+	rss := residentBytes()
+	g := runtime.NumGoroutine()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peakRSS = max(s.peakRSS, rss)
+	s.peakGoroutines = max(s.peakGoroutines, g)
+}
+
+// Stop ends sampling and returns the usage of a phase that completed ops
+// operations moving payloadBytes of object data.
+func (s *Sampler) Stop(ops int, payloadBytes int64) Usage {
+	close(s.stop)
+	<-s.stopped
+	s.sample()
+	end := read()
+
+	u := Usage{
+		CPUUserSeconds:   (end.user - s.begin.user).Seconds(),
+		CPUSystemSeconds: (end.system - s.begin.system).Seconds(),
+		PeakRSSBytes:     s.peakRSS,
+		PeakGoroutines:   s.peakGoroutines,
+		GCCycles:         end.mem.NumGC - s.begin.mem.NumGC,
+		GCPauseTotalMs:   float64(end.mem.PauseTotalNs-s.begin.mem.PauseTotalNs) / 1e6,
+		AllocBytes:       end.mem.TotalAlloc - s.begin.mem.TotalAlloc,
+		NetRxBytes:       end.rx - s.begin.rx,
+		NetTxBytes:       end.tx - s.begin.tx,
+	}
+	u.CPUSeconds = u.CPUUserSeconds + u.CPUSystemSeconds
+	// PauseNs is a ring of the most recent 256 pauses.
+	for i := uint32(0); i < u.GCCycles && i < uint32(len(end.mem.PauseNs)); i++ {
+		pause := float64(end.mem.PauseNs[(end.mem.NumGC-1-i)%uint32(len(end.mem.PauseNs))]) / 1e6
+		u.GCPauseMaxMs = max(u.GCPauseMaxMs, pause)
+	}
+	if payloadBytes > 0 {
+		u.CPUSecondsPerGB = u.CPUSeconds / (float64(payloadBytes) / (1024 * 1024 * 1024))
+		u.NetBytesPerPayload = float64(u.NetRxBytes+u.NetTxBytes) / float64(payloadBytes)
+	}
+	if ops > 0 {
+		u.AllocBytesPerOp = float64(u.AllocBytes) / float64(ops)
+	}
+	return u
+}
+
+// residentBytes returns the process's current resident set size.
+func residentBytes() int64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * int64(os.Getpagesize())
+}
+
+// NetReceived returns a counter of the bytes received over every interface
+// except loopback since the call. It shows the progress of transfers whose
+// client does not expose the stream, and reads zero where /proc/net/dev is
+// missing.
+func NetReceived() func() int64 {
+	start, _ := netBytes()
+	return func() int64 {
+		rx, _ := netBytes()
+		return rx - start
+	}
+}
+
+// NetSent is like NetReceived for bytes sent.
+func NetSent() func() int64 {
+	_, start := netBytes()
+	return func() int64 {
+		_, tx := netBytes()
+		return tx - start
+	}
+}
+
+// netBytes sums received and transmitted bytes over every interface except
+// loopback.
+func netBytes() (rx, tx int64) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		// Receive bytes is the first field and transmit bytes the ninth.
+		fields := strings.Fields(rest)
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseInt(fields[0], 10, 64)
+		t, _ := strconv.ParseInt(fields[8], 10, 64)
+		rx += r
+		tx += t
+	}
+	return rx, tx
+}
+
+// Print writes u in the same layout as the latency metrics.
+func Print(w io.Writer, phase string, u Usage) {
+	fmt.Fprintf(w, "\nClient Resources (%s phase):\n", phase)
+	fmt.Fprintf(w, "CPU Time: %.3f s (user %.3f s, system %.3f s)\n", u.CPUSeconds, u.CPUUserSeconds, u.CPUSystemSeconds)
+	fmt.Fprintf(w, "Peak RSS: %.2f MB\n", float64(u.PeakRSSBytes)/(1024*1024))
+	fmt.Fprintf(w, "Peak Goroutines: %d\n", u.PeakGoroutines)
+	fmt.Fprintf(w, "GC: %d cycles, %.2f ms total pause, %.2f ms max pause\n", u.GCCycles, u.GCPauseTotalMs, u.GCPauseMaxMs)
+	fmt.Fprintf(w, "Allocated: %.2f MB (%.0f bytes/op)\n", float64(u.AllocBytes)/(1024*1024), u.AllocBytesPerOp)
+	fmt.Fprintf(w, "Network: %.2f MB received, %.2f MB sent\n", float64(u.NetRxBytes)/(1024*1024), float64(u.NetTxBytes)/(1024*1024))
+	if u.CPUSecondsPerGB > 0 {
+		fmt.Fprintf(w, "CPU per GB transferred: %.3f s\n", u.CPUSecondsPerGB)
+	}
+}
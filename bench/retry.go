@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+)
+
+// retryFlags registers the flags of the retry policy operations run under.
+func retryFlags(fs *flag.FlagSet) *retry.Policy {
+	p := retry.Default()
+	fs.IntVar(&p.MaxAttempts, "retry-max-attempts", p.MaxAttempts, "attempts per operation, the first included; 1 turns retries off")
+	fs.StringVar(&p.Backoff, "retry-backoff", p.Backoff, "wait between attempts: "+strings.Join(retry.Backoffs(), ", "))
+	fs.DurationVar(&p.BaseDelay, "retry-base-delay", p.BaseDelay, "wait before the first retry; exponential backoff doubles it for each retry after")
+	fs.DurationVar(&p.MaxDelay, "retry-max-delay", p.MaxDelay, "longest wait between attempts with exponential backoff")
+	fs.Func("retry-on", "comma-separated error classes to retry: "+strings.Join(retry.Classes(), ", ")+
+		" (default \""+strings.Join(retry.DefaultClasses, ",")+"\")", func(s string) error {
+		p.Classes = nil
+		for _, c := range strings.Split(s, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				p.Classes = append(p.Classes, c)
+			}
+		}
+		return nil
+	})
+	return &p
+}
@@ -0,0 +1,179 @@
+// Package retry retries benchmark operations under one policy for every
+// backend, and reports how many attempts each took. The SDKs' own retries
+// are turned off where they can be, so a retried request is counted as such
+// instead of looking like a slow one.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+// Backoff strategies.
+const (
+	// BackoffExponential waits a random time up to BaseDelay doubled for
+	// each retry, capped at MaxDelay ("full jitter").
+	BackoffExponential = "exponential"
+	// BackoffConstant waits BaseDelay before every retry.
+	BackoffConstant = "constant"
+	// BackoffNone retries immediately.
+	BackoffNone = "none"
+)
+
+// Defaults match the attempts and retried errors of the AWS SDK's standard
+// retryer.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = BackoffExponential
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 20 * time.Second
+)
+
+// DefaultClasses are the error classes retried by default.
+var DefaultClasses = []string{backend.ClassThrottled, backend.ClassTimeout, backend.ClassServer, backend.ClassNetwork}
+
+// Policy decides whether and when a failed operation is tried again. The
+// zero value makes a single attempt.
+type Policy struct {
+	// MaxAttempts includes the first attempt; 1 turns retries off.
+	MaxAttempts int           `json:"max_attempts"`
+	Backoff     string        `json:"backoff,omitempty"`
+	BaseDelay   time.Duration `json:"base_delay,omitempty"`
+	MaxDelay    time.Duration `json:"max_delay,omitempty"`
+	// Classes lists the backend.ErrorClass values that are retried.
+	Classes []string `json:"classes,omitempty"`
+}
+
+// Default returns the default policy.
+func Default() Policy {
+	return Policy{
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		Classes:     slices.Clone(DefaultClasses),
+	}
+}
+
+// Backoffs returns the names of the backoff strategies.
+func Backoffs() []string {
+	return []string{BackoffExponential, BackoffConstant, BackoffNone}
+}
+
+// Validate reports settings that cannot be used.
+func (p Policy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max attempts must not be negative")
+	}
+	if p.Backoff != "" && !slices.Contains(Backoffs(), p.Backoff) {
+		return fmt.Errorf("unknown backoff %q (available: %s)", p.Backoff, strings.Join(Backoffs(), ", "))
+	}
+	if p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return fmt.Errorf("retry delays must not be negative")
+	}
+	for _, c := range p.Classes {
+		if !slices.Contains(Classes(), c) {
+			return fmt.Errorf("unknown error class %q (available: %s)", c, strings.Join(Classes(), ", "))
+		}
+	}
+	return nil
+}
+
+// Classes returns the error classes a policy may retry.
+func Classes() []string {
+	return []string{
+		backend.ClassThrottled, backend.ClassTimeout, backend.ClassServer, backend.ClassNetwork,
+		backend.ClassClient, backend.ClassNotFound, backend.ClassOther,
+	}
+}
+
+// String describes p compactly, e.g. "3 attempts, exponential backoff from
+// 100ms up to 20s, on throttled, timeout".
+func (p Policy) String() string {
+	if p.MaxAttempts <= 1 {
+		return "no retries"
+	}
+	var backoff string
+	switch p.Backoff {
+	case BackoffExponential:
+		backoff = fmt.Sprintf("exponential backoff from %s up to %s", p.BaseDelay, p.MaxDelay)
+	case BackoffConstant:
+		backoff = fmt.Sprintf("constant backoff of %s", p.BaseDelay)
+	default:
+		backoff = "no backoff"
+	}
+	return fmt.Sprintf("%d attempts, %s, on %s", p.MaxAttempts, backoff, strings.Join(p.Classes, ", "))
+}
+
+// retryable reports whether an attempt that failed with err may be retried.
+func (p Policy) retryable(err error) bool {
+	return slices.Contains(p.Classes, backend.ErrorClass(err))
+}
+
+// delay returns the wait before retry n, counting from 1.
+func (p Policy) delay(n int) time.Duration {
+	switch p.Backoff {
+	case BackoffExponential:
+		ceiling := p.BaseDelay
+		for i := 1; i < n && ceiling < p.MaxDelay; i++ {
+			ceiling *= 2
+		}
+		ceiling = min(ceiling, p.MaxDelay)
+		if ceiling <= 0 {
+			return 0
+		}
+		return rand.N(ceiling + 1)
+	case BackoffConstant:
+		return p.BaseDelay
+	}
+	return 0
+}
+
+// Attempts describes how an operation went under a policy.
+type Attempts struct {
+	// Count is the number of attempts made.
+	Count int
+	// First is the latency of the first attempt and FirstErr its error.
+	First    time.Duration
+	FirstErr error
+	// Retrying is the time spent after the first attempt, backoff included.
+	Retrying time.Duration
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retried,
+// the attempts run out or ctx is done. It returns the last error.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) (Attempts, error) {
+	start := time.Now()
+	err := fn(ctx)
+	a := Attempts{Count: 1, First: time.Since(start), FirstErr: err}
+	for err != nil && a.Count < p.MaxAttempts && p.retryable(err) && ctx.Err() == nil {
+		if !sleep(ctx, p.delay(a.Count)) {
+			break
+		}
+		a.Count++
+		err = fn(ctx)
+	}
+	a.Retrying = time.Since(start) - a.First
+	return a, err
+}
+
+// sleep waits for d and reports false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+var (
+	errThrottled = &smithy.GenericAPIError{Code: "SlowDown", Message: "slow down"}
+	errNotFound  = fmt.Errorf("object b/k: %w", backend.ErrNotFound)
+)
+
+func TestDo(t *testing.T) {
+	throttledOnly := Policy{MaxAttempts: 3, Backoff: BackoffNone, Classes: []string{backend.ClassThrottled}}
+	tests := []struct {
+		name   string
+		policy Policy
+		// errs are the results of the successive attempts; attempts past
+		// the end succeed.
+		errs      []error
+		wantCount int
+		wantErr   error
+	}{
+		{"success", throttledOnly, nil, 1, nil},
+		{"retried then success", throttledOnly, []error{errThrottled, errThrottled}, 3, nil},
+		{"attempts run out", throttledOnly, []error{errThrottled, errThrottled, errThrottled, errThrottled}, 3, errThrottled},
+		{"class not retried", throttledOnly, []error{errNotFound}, 1, errNotFound},
+		{"stops at a class not retried", throttledOnly, []error{errThrottled, errNotFound, errThrottled}, 2, errNotFound},
+		{"class retried", Policy{MaxAttempts: 2, Classes: []string{backend.ClassNotFound}}, []error{errNotFound}, 2, nil},
+		{"zero policy", Policy{}, []error{errThrottled}, 1, errThrottled},
+		{"one attempt", Policy{MaxAttempts: 1, Classes: Classes()}, []error{errThrottled}, 1, errThrottled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			a, err := tt.policy.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if a.Count != tt.wantCount || calls != tt.wantCount {
+				t.Errorf("Count = %d after %d calls, want %d", a.Count, calls, tt.wantCount)
+			}
+			var wantFirst error
+			if len(tt.errs) > 0 {
+				wantFirst = tt.errs[0]
+			}
+			if a.FirstErr != wantFirst {
+				t.Errorf("FirstErr = %v, want %v", a.FirstErr, wantFirst)
+			}
+		})
+	}
+}
+
+func TestDoStopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, Backoff: BackoffConstant, BaseDelay: time.Hour, Classes: []string{backend.ClassThrottled}}
+	go cancel()
+	a, err := p.Do(ctx, func(ctx context.Context) error { return errThrottled })
+	if a.Count != 1 || err != errThrottled {
+		t.Errorf("Do = %d attempts, %v; want 1, %v", a.Count, err, errThrottled)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		n      int
+		max    time.Duration
+	}{
+		{Policy{Backoff: BackoffNone, BaseDelay: time.Second}, 3, 0},
+		{Policy{Backoff: BackoffConstant, BaseDelay: time.Second}, 3, time.Second},
+		{Policy{Backoff: BackoffExponential, BaseDelay: time.Second, MaxDelay: time.Minute}, 1, time.Second},
+		{Policy{Backoff: BackoffExponential, BaseDelay: time.Second, MaxDelay: time.Minute}, 3, 4 * time.Second},
+		{Policy{Backoff: BackoffExponential, BaseDelay: time.Second, MaxDelay: 5 * time.Second}, 10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		for range 100 {
+			if d := tt.policy.delay(tt.n); d < 0 || d > tt.max {
+				t.Fatalf("%+v: delay(%d) = %s, want in [0, %s]", tt.policy, tt.n, d, tt.max)
+			}
+		}
+	}
+	if d := (Policy{Backoff: BackoffConstant, BaseDelay: time.Second}).delay(2); d != time.Second {
+		t.Errorf("constant delay = %s, want 1s", d)
+	}
+}
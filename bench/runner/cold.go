@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+)
+
+// Cold-start variants, recorded alongside each operation made with a fresh
+// client. Together they add up to the operation's latency.
+const (
+	variantConstruct   = "cold: client construction"
+	variantCredentials = "cold: credential resolution"
+	variantConnect     = "cold: connection setup"
+	variantRequest     = "cold: request"
+)
+
+// coldStart is where the time of an operation with a fresh client went.
+// Credentials and Connect are only measured for clients that resolve
+// credentials lazily and connect over net/http; otherwise that time falls
+// under Construct or Request.
+type coldStart struct {
+	Construct, Credentials, Connect, Request time.Duration
+	credentials, connect                     bool
+}
+
+// coldUnmeasured returns the cold-start variants that clients of b give no
+// separate figure for: credential resolution unless b resolves credentials
+// on request, and connection setup unless b connects over net/http.
+func coldUnmeasured(b backend.Backend) []string {
+	var vs []string
+	if _, ok := b.(backend.CredentialResolver); !ok {
+		vs = append(vs, variantCredentials)
+	}
+	if !backend.AppliesTransport(b.Name()) {
+		vs = append(vs, variantConnect)
+	}
+	return vs
+}
+
+// coldCall opens a fresh client with is.newClient, runs fn with it and
+// closes it again.
+func (is issuer) coldCall(ctx context.Context, fn func(ctx context.Context, b backend.Backend) error) (*coldStart, error) {
+	var cs coldStart
+	start := time.Now()
+	b, err := is.newClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct client: %w", err)
+	}
+	defer b.Close()
+	cs.Construct = time.Since(start)
+
+	start = time.Now()
+	cs.credentials, err = backend.ResolveCredentials(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	cs.Credentials = time.Since(start)
+
+	var ct connTimer
+	start = time.Now()
+	err = fn(httptrace.WithClientTrace(ctx, ct.trace()), b)
+	cs.Connect, cs.connect = ct.result()
+	cs.Request = time.Since(start) - cs.Connect
+	return &cs, err
+}
+
+// record adds the breakdown of a successful operation that started at start
+// to the cold-start variants of op.
+func (cs *coldStart) record(set *stats.Set, op string, size int64, start time.Time, bytes int64) {
+	add := func(variant string, d time.Duration, bytes int64) {
+		set.Recorder(stats.Key{Op: op, Variant: variant, Size: size}).Record(start, d, bytes, nil)
+		start = start.Add(d)
+	}
+	add(variantConstruct, cs.Construct, 0)
+	if cs.credentials {
+		add(variantCredentials, cs.Credentials, 0)
+	}
+	if cs.connect {
+		add(variantConnect, cs.Connect, 0)
+	}
+	add(variantRequest, cs.Request, bytes)
+}
+
+// connTimer sums the time requests spend waiting for new connections: DNS
+// resolution, TCP connect and the TLS handshake.
+type connTimer struct {
+	mu    sync.Mutex
+	start time.Time
+	total time.Duration
+	used  bool
+}
+
+func (c *connTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			c.mu.Lock()
+			c.start = time.Now()
+			c.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if !info.Reused && !c.start.IsZero() {
+				c.total += time.Since(c.start)
+			}
+			c.used = true
+		},
+	}
+}
+
+// result returns the time spent connecting, and whether the client made
+// its requests over net/http at all.
+func (c *connTimer) result() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total, c.used
+}
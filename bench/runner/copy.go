@@ -0,0 +1,284 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// Phases of a copy run after the load phase.
+const (
+	PhaseCopy   = "copy"
+	PhaseRename = "rename"
+)
+
+// Operations of a copy run, as reported in results.
+const (
+	OpCopy = "COPY"
+	// OpRename is a copy followed by a delete of the source, the way a
+	// move between prefixes is done on object storage.
+	OpRename = "RENAME"
+)
+
+// MinPartSize is the smallest part S3 accepts in a multipart upload, other
+// than the last.
+const MinPartSize = 5 << 20
+
+// Copy measures copying objects within a bucket and renaming them by copy
+// then delete, for each of several object sizes. Objects are copied on the
+// server where the backend can, and read and written back where it cannot.
+type Copy struct {
+	Backend backend.Backend
+	// Sizes are the object sizes in bytes.
+	Sizes []int
+	// Objects is the number of objects written per size; each is renamed
+	// once in the rename phase.
+	Objects int
+	// Operations is the number of copies per size in the copy phase.
+	Operations int
+	// PartThreshold is the size from which objects are copied in parts of
+	// PartSize bytes, on backends that can; 0 copies every object whole.
+	PartThreshold int64
+	PartSize      int64
+	// Threads is the number of concurrent workers.
+	Threads int
+	// Seed makes payloads and source choice reproducible.
+	Seed int64
+	Options
+
+	ownedBucket
+	rttMs   float64
+	bucket  string
+	methods []string
+}
+
+// CopyInfo describes a copy run in its result.
+type CopyInfo struct {
+	Objects       int          `json:"objects"`
+	Operations    int          `json:"operations"`
+	Threads       int          `json:"threads"`
+	PartThreshold int64        `json:"part_threshold,omitempty"`
+	PartSize      int64        `json:"part_size,omitempty"`
+	Methods       []CopyMethod `json:"methods"`
+}
+
+// CopyMethod records how objects of one size were copied.
+type CopyMethod struct {
+	Size   int    `json:"size"`
+	Method string `json:"method"`
+}
+
+// method returns how objects of size bytes were copied, and the part size
+// of multipart copies.
+func (info *CopyInfo) method(size int64) (string, int64) {
+	if info == nil {
+		return backend.CopyServer, 0
+	}
+	for _, m := range info.Methods {
+		if int64(m.Size) == size {
+			return m.Method, info.PartSize
+		}
+	}
+	return backend.CopyServer, info.PartSize
+}
+
+// Scenario names the run in metrics and output files.
+func (c *Copy) Scenario() string {
+	return "copy"
+}
+
+// Validate reports settings that cannot be run.
+func (c *Copy) Validate() error {
+	switch {
+	case len(c.Sizes) == 0:
+		return errors.New("at least one object size is required")
+	case slices.Min(c.Sizes) < 0:
+		return fmt.Errorf("object size must not be negative, got %d", slices.Min(c.Sizes))
+	case c.Objects <= 0:
+		return fmt.Errorf("objects must be positive, got %d", c.Objects)
+	case c.Operations < 0:
+		return fmt.Errorf("operations must not be negative, got %d", c.Operations)
+	case c.Threads <= 0:
+		return fmt.Errorf("threads must be positive, got %d", c.Threads)
+	case c.PartThreshold < 0:
+		return fmt.Errorf("part threshold must not be negative, got %d", c.PartThreshold)
+	case c.PartThreshold > 0 && c.PartSize < MinPartSize:
+		return fmt.Errorf("part size must be at least %d bytes, got %d", MinPartSize, c.PartSize)
+	}
+	return nil
+}
+
+func (c *Copy) instruments() instruments {
+	return instruments{
+		out:      c.Out,
+		backend:  c.Backend.Name(),
+		scenario: c.Scenario(),
+		metrics:  c.Metrics,
+		profile:  c.Profile,
+		rttMs:    c.rttMs,
+	}
+}
+
+// issuer returns the issuer of the run's calls.
+func (c *Copy) issuer() issuer {
+	return c.issuerFor(c.Backend, c.bucket)
+}
+
+// Run creates a bucket, writes the objects, copies and renames them and
+// prints the metrics of each phase; Close removes the bucket again.
+func (c *Copy) Run(ctx context.Context) (*Result, error) {
+	if c.Out == nil {
+		c.Out = os.Stdout
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.Profile.Check(PhaseLoad, PhaseCopy, PhaseRename); err != nil {
+		return nil, err
+	}
+
+	info := &CopyInfo{
+		Objects:       c.Objects,
+		Operations:    c.Operations,
+		Threads:       c.Threads,
+		PartThreshold: c.PartThreshold,
+	}
+	if c.PartThreshold > 0 {
+		info.PartSize = c.PartSize
+	}
+	c.methods = nil
+	for _, size := range c.Sizes {
+		method := backend.CopyMethod(c.Backend, int64(size), c.PartThreshold)
+		c.methods = append(c.methods, method)
+		info.Methods = append(info.Methods, CopyMethod{Size: size, Method: method})
+	}
+	result := &Result{
+		Backend:    c.Backend.Name(),
+		Scenario:   c.Scenario(),
+		Copy:       info,
+		Seed:       c.Seed,
+		Retry:      c.Retry,
+		Timeout:    c.Timeout,
+		DeadlineMs: ms(c.Deadline),
+		StartedAt:  time.Now(),
+	}
+
+	result.Environment = environment.Capture(ctx, c.Backend)
+	result.Environment.Print(c.Out)
+	fmt.Fprintf(c.Out, "Retry policy: %s\n", c.Retry)
+	fmt.Fprintf(c.Out, "Operation timeout: %s\n", c.Timeout)
+	for _, m := range info.Methods {
+		fmt.Fprintf(c.Out, "Copy method for %d bytes: %s\n", m.Size, m.Method)
+	}
+
+	c.bucket = backend.BucketName(c.Backend, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+	result.Bucket = c.bucket
+	fmt.Fprintf(c.Out, "Creating bucket: %s\n", c.bucket)
+	if err := c.Backend.CreateBucket(ctx, c.bucket); err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+	c.own(ctx, c.Out, c.Cleanup, c.Backend, c.bucket)
+	// Multipart copies track their uploads under the bucket.
+	ctx = cleanup.WithResource(ctx, c.ownedBucket.bucket)
+
+	result.Network = probe(ctx, c.Out, c.Probe, c.Backend, c.bucket)
+	c.rttMs = result.Network.RTTMs()
+
+	phaseCtx, cancel := withDeadline(ctx, c.Deadline)
+	defer cancel()
+
+	objects := c.Objects * len(c.Sizes)
+	capture := c.instruments().startProfile(profile.WholeRun)
+	fmt.Fprintf(c.Out, "\n===== LOAD PHASE =====\n")
+	fmt.Fprintf(c.Out, "\nWriting %d objects of each size with %d threads\n", c.Objects, c.Threads)
+	result.Phases = append(result.Phases, c.phase(phaseCtx, PhaseLoad, string(trace.OpPut), objects, c.load))
+
+	if c.Operations > 0 && phaseCtx.Err() == nil {
+		fmt.Fprintf(c.Out, "\n===== COPY PHASE =====\n")
+		fmt.Fprintf(c.Out, "\nCopying %d objects of each size with %d threads\n", c.Operations, c.Threads)
+		result.Phases = append(result.Phases, c.phase(phaseCtx, PhaseCopy, OpCopy, c.Operations*len(c.Sizes), c.copy))
+	}
+	if phaseCtx.Err() == nil {
+		fmt.Fprintf(c.Out, "\n===== RENAME PHASE =====\n")
+		fmt.Fprintf(c.Out, "\nRenaming %d objects of each size by copy and delete with %d threads\n", c.Objects, c.Threads)
+		result.Phases = append(result.Phases, c.phase(phaseCtx, PhaseRename, OpRename, objects, c.rename))
+	}
+	result.Profiles = c.instruments().stopProfile(capture)
+	result.DeadlineReached = deadlineReached(phaseCtx, c.Out, c.Deadline)
+	result.Interrupted = interrupted(ctx, c.Out)
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// copyOp issues the i-th call of a phase on an object of the size at
+// index s of Sizes.
+type copyOp func(ctx context.Context, ps *phaseState, rng *rand.Rand, data []byte, s, i int)
+
+// phase issues n calls of op spread over the threads, taking the sizes in
+// turn.
+func (c *Copy) phase(ctx context.Context, name, op string, n int, call copyOp) PhaseResult {
+	ps := startPhase(c.instruments(), name, n)
+	// Create recorders up front so metrics print in size order.
+	for _, size := range c.Sizes {
+		ps.set.Recorder(stats.Key{Op: op, Size: int64(size)})
+	}
+	parallel(ctx, n, c.Threads, c.Seed, func(rng *rand.Rand) func(i int) {
+		var data []byte
+		if name == PhaseLoad {
+			data = make([]byte, slices.Max(c.Sizes))
+		}
+		return func(i int) { call(ctx, ps, rng, data, i%len(c.Sizes), i/len(c.Sizes)) }
+	})
+	return ps.finish(ctx)
+}
+
+// copyKey returns the key of object i of the given size under prefix.
+func copyKey(prefix string, size, i int) string {
+	return fmt.Sprintf("%s-%d/obj-%d", prefix, size, i)
+}
+
+// load writes object i of the s-th size.
+func (c *Copy) load(ctx context.Context, ps *phaseState, rng *rand.Rand, data []byte, s, i int) {
+	size := c.Sizes[s]
+	key := copyKey("src", size, i)
+	data = data[:size]
+	rng.Read(data)
+	c.issuer().exec(ctx, ps, string(trace.OpPut), nil, key, int64(size), int64(size), func(ctx context.Context, b backend.Backend) error {
+		return b.PutObject(ctx, c.bucket, key, data)
+	})
+}
+
+// copy makes the i-th copy of a random object of the s-th size.
+func (c *Copy) copy(ctx context.Context, ps *phaseState, rng *rand.Rand, _ []byte, s, i int) {
+	size := c.Sizes[s]
+	src := copyKey("src", size, rng.Intn(c.Objects))
+	dst := copyKey("copy", size, i)
+	c.issuer().exec(ctx, ps, OpCopy, nil, dst, int64(size), int64(size), func(ctx context.Context, b backend.Backend) error {
+		return backend.CopyObject(ctx, b, c.bucket, src, dst, c.methods[s], int64(size), c.PartSize)
+	})
+}
+
+// rename moves object i of the s-th size to another prefix.
+func (c *Copy) rename(ctx context.Context, ps *phaseState, _ *rand.Rand, _ []byte, s, i int) {
+	size := c.Sizes[s]
+	src := copyKey("src", size, i)
+	dst := copyKey("moved", size, i)
+	c.issuer().exec(ctx, ps, OpRename, nil, dst, int64(size), int64(size), func(ctx context.Context, b backend.Backend) error {
+		if err := backend.CopyObject(ctx, b, c.bucket, src, dst, c.methods[s], int64(size), c.PartSize); err != nil {
+			return err
+		}
+		return b.DeleteObject(ctx, c.bucket, src)
+	})
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/tracing"
+)
+
+// issuer times single backend calls for the scenarios: each goes through
+// an operation span, the timeout and retry policies, and is recorded in the
+// phase's stats, metrics and progress.
+type issuer struct {
+	backend backend.Backend
+	bucket  string
+	retry   retry.Policy
+	timeout timeout.Policy
+	out     io.Writer
+	// newClient, when set, gives each call a fresh client, and the call's
+	// latency is also broken down into the cold-start variants.
+	newClient func(ctx context.Context) (backend.Backend, error)
+	// unsized records latencies without the object size, for runs whose
+	// sizes are not a fixed set, such as trace replays.
+	unsized bool
+}
+
+// exec runs fn inside an operation span and records its latency under op,
+// and also under each non-empty variant. fn is handed the client to use. It
+// returns fn's final error.
+func (is issuer) exec(ctx context.Context, ps *phaseState, op string, variants []string, target string, size, bytes int64, fn func(ctx context.Context, b backend.Backend) error) error {
+	ctx, span := tracing.Start(ctx, op,
+		tracing.AttrBackend.String(is.backend.Name()),
+		tracing.AttrPhase.String(ps.name),
+		tracing.AttrBucket.String(is.bucket),
+		tracing.AttrKey.String(target),
+		tracing.AttrSize.Int64(size))
+	variants = slices.DeleteFunc(slices.Clone(variants), func(v string) bool { return v == "" })
+	if len(variants) > 0 {
+		span.SetAttributes(tracing.AttrVariant.String(strings.Join(variants, ", ")))
+	}
+	opCtx, cancel := is.timeout.Context(ctx, op, bytes)
+	defer cancel()
+	end := ps.metrics.Begin()
+	start := time.Now()
+	var attempts retry.Attempts
+	call := func(ctx context.Context, b backend.Backend) error {
+		var err error
+		attempts, err = is.retry.Do(ctx, func(ctx context.Context) error { return fn(ctx, b) })
+		return err
+	}
+	var cold *coldStart
+	var err error
+	if is.newClient != nil {
+		cold, err = is.coldCall(opCtx, call)
+	} else {
+		err = call(opCtx, is.backend)
+	}
+	latency := time.Since(start)
+	end()
+	timedOut := err != nil && timeout.Expired(opCtx)
+	if timedOut {
+		err = context.Cause(opCtx)
+	}
+	tracing.End(ctx, span, attempts.Count, err, backend.ErrorClass(err))
+	if err != nil && ctx.Err() != nil {
+		// Cut off by the end of the run, not a failure of the backend.
+		return err
+	}
+	if err != nil {
+		bytes = 0
+	}
+	if is.unsized {
+		size = 0
+	}
+	rec := ps.set.Recorder(stats.Key{Op: op, Size: size})
+	record(rec, start, latency, bytes, err, timedOut)
+	recordAttempts(ps, rec, stats.Key{Op: op, Variant: stats.VariantFirstAttempt, Size: size}, start, attempts, bytes, timedOut)
+	for _, v := range variants {
+		record(ps.set.Recorder(stats.Key{Op: op, Variant: v, Size: size}), start, latency, bytes, err, timedOut)
+	}
+	if cold != nil && err == nil {
+		cold.record(ps.set, op, size, start, bytes)
+	}
+	ps.tracker.Observe(latency, bytes, err)
+	ps.metrics.Observe(op, size, latency, bytes, err)
+	if err != nil {
+		fmt.Fprintf(is.out, "%s failed for %s: %v\n", op, target, err)
+	}
+	return err
+}
+
+// parallel makes calls 0 to n-1 spread over threads workers, stopping early
+// once ctx is done. newWorker is called once per worker, in the worker's
+// goroutine, with the worker's own random source and returns the function
+// making its calls; any calls it makes itself run alongside the others'.
+func parallel(ctx context.Context, n, threads int, seed int64, newWorker func(rng *rand.Rand) func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for t := range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call := newWorker(rand.New(rand.NewSource(seed + int64(t))))
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n || ctx.Err() != nil {
+					return
+				}
+				call(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
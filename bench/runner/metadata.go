@@ -0,0 +1,313 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// PhaseMetadata is the phase of a metadata run that issues the metadata
+// calls; objects are written in the load phase before it.
+const PhaseMetadata = "metadata"
+
+// MaxMetadataSize is the most user metadata S3 stores with one object,
+// counting keys and values.
+const MaxMetadataSize = 2048
+
+// Variants of the metadata calls, reported alongside the overall figures.
+const (
+	variantExistingKey = "existing key"
+	variantMissingKey  = "missing key"
+)
+
+// Metadata measures stat-like calls: HeadObject on existing and missing
+// keys, on objects carrying user metadata of several sizes, and HeadBucket.
+// GETs of the same objects are issued alongside for reference.
+type Metadata struct {
+	Backend backend.Backend
+	// Objects is the number of objects written per metadata size.
+	Objects int
+	// Size is the size of each object in bytes.
+	Size int
+	// MetadataSizes are the sizes in bytes of the user metadata stored
+	// with the objects; 0 stores none. Backends that cannot store user
+	// metadata use 0 only.
+	MetadataSizes []int
+	// Operations is the number of calls of each kind in the metadata phase.
+	Operations int
+	// Threads is the number of concurrent workers.
+	Threads int
+	// Seed makes payloads and key choice reproducible.
+	Seed int64
+	Options
+
+	ownedBucket
+	rttMs  float64
+	bucket string
+	sizes  []int
+}
+
+// MetadataInfo describes a metadata run in its result.
+type MetadataInfo struct {
+	Objects       int   `json:"objects"`
+	ObjectSize    int   `json:"object_size"`
+	MetadataSizes []int `json:"metadata_sizes"`
+	Operations    int   `json:"operations"`
+	Threads       int   `json:"threads"`
+}
+
+// Scenario names the run in metrics and output files.
+func (m *Metadata) Scenario() string {
+	return "metadata"
+}
+
+// Validate reports settings that cannot be run.
+func (m *Metadata) Validate() error {
+	switch {
+	case m.Objects <= 0:
+		return fmt.Errorf("objects must be positive, got %d", m.Objects)
+	case m.Size < 0:
+		return fmt.Errorf("object size must not be negative, got %d", m.Size)
+	case m.Operations < 0:
+		return fmt.Errorf("operations must not be negative, got %d", m.Operations)
+	case m.Threads <= 0:
+		return fmt.Errorf("threads must be positive, got %d", m.Threads)
+	case len(m.MetadataSizes) == 0:
+		return errors.New("at least one metadata size is required")
+	}
+	for _, n := range m.MetadataSizes {
+		if n < 0 || n > MaxMetadataSize {
+			return fmt.Errorf("metadata size %d is outside 0 to %d bytes", n, MaxMetadataSize)
+		}
+	}
+	return nil
+}
+
+func (m *Metadata) instruments() instruments {
+	return instruments{
+		out:      m.Out,
+		backend:  m.Backend.Name(),
+		scenario: m.Scenario(),
+		metrics:  m.Metrics,
+		profile:  m.Profile,
+		rttMs:    m.rttMs,
+	}
+}
+
+// Run creates a bucket, writes the objects, issues the metadata calls and
+// prints their metrics; Close removes the bucket again.
+func (m *Metadata) Run(ctx context.Context) (*Result, error) {
+	if m.Out == nil {
+		m.Out = os.Stdout
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Profile.Check(PhaseLoad, PhaseMetadata); err != nil {
+		return nil, err
+	}
+	if _, ok := m.Backend.(backend.Inspector); !ok {
+		return nil, fmt.Errorf("backend %s has no HeadObject or HeadBucket call", m.Backend.Name())
+	}
+	m.sizes = m.MetadataSizes
+	if !backend.SupportsMetadata(m.Backend) && !(len(m.sizes) == 1 && m.sizes[0] == 0) {
+		fmt.Fprintf(m.Out, "Backend %s cannot store user metadata; writing objects without it\n", m.Backend.Name())
+		m.sizes = []int{0}
+	}
+
+	result := &Result{
+		Backend:  m.Backend.Name(),
+		Scenario: m.Scenario(),
+		Metadata: &MetadataInfo{
+			Objects:       m.Objects,
+			ObjectSize:    m.Size,
+			MetadataSizes: m.sizes,
+			Operations:    m.Operations,
+			Threads:       m.Threads,
+		},
+		Seed:       m.Seed,
+		Retry:      m.Retry,
+		Timeout:    m.Timeout,
+		DeadlineMs: ms(m.Deadline),
+		StartedAt:  time.Now(),
+	}
+
+	result.Environment = environment.Capture(ctx, m.Backend)
+	result.Environment.Print(m.Out)
+	fmt.Fprintf(m.Out, "Retry policy: %s\n", m.Retry)
+	fmt.Fprintf(m.Out, "Operation timeout: %s\n", m.Timeout)
+
+	m.bucket = backend.BucketName(m.Backend, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+	result.Bucket = m.bucket
+	fmt.Fprintf(m.Out, "Creating bucket: %s\n", m.bucket)
+	if err := m.Backend.CreateBucket(ctx, m.bucket); err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+	m.own(ctx, m.Out, m.Cleanup, m.Backend, m.bucket)
+
+	result.Network = probe(ctx, m.Out, m.Probe, m.Backend, m.bucket)
+	m.rttMs = result.Network.RTTMs()
+
+	phaseCtx, cancel := withDeadline(ctx, m.Deadline)
+	defer cancel()
+
+	capture := m.instruments().startProfile(profile.WholeRun)
+	fmt.Fprintf(m.Out, "\n===== LOAD PHASE =====\n")
+	fmt.Fprintf(m.Out, "\nWriting %d objects of size %d bytes for each metadata size (%s) with %d threads\n",
+		m.Objects, m.Size, m.sizeList(), m.Threads)
+	result.Phases = append(result.Phases, m.phase(phaseCtx, PhaseLoad, m.Objects*len(m.sizes), m.load))
+
+	if m.Operations > 0 && phaseCtx.Err() == nil {
+		fmt.Fprintf(m.Out, "\n===== METADATA PHASE =====\n")
+		fmt.Fprintf(m.Out, "\nIssuing %d each of HEAD on existing keys, HEAD on missing keys, HEAD_BUCKET and GET with %d threads\n",
+			m.Operations, m.Threads)
+		result.Phases = append(result.Phases, m.phase(phaseCtx, PhaseMetadata, 4*m.Operations, m.inspect))
+	}
+	result.Profiles = m.instruments().stopProfile(capture)
+	result.DeadlineReached = deadlineReached(phaseCtx, m.Out, m.Deadline)
+	result.Interrupted = interrupted(ctx, m.Out)
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// sizeList formats the metadata sizes in use, e.g. "0, 256, 2048 bytes".
+func (m *Metadata) sizeList() string {
+	var s []string
+	for _, n := range m.sizes {
+		s = append(s, fmt.Sprint(n))
+	}
+	return strings.Join(s, ", ") + " bytes"
+}
+
+// metadataOp issues the i-th call of a phase using rng.
+type metadataOp func(ctx context.Context, ps *phaseState, rng *rand.Rand, data []byte, i int)
+
+// phase issues n calls spread over the threads.
+func (m *Metadata) phase(ctx context.Context, name string, n int, op metadataOp) PhaseResult {
+	ps := startPhase(m.instruments(), name, n)
+	// Create recorders up front so metrics print in a fixed order.
+	for _, k := range m.keys(name) {
+		ps.set.Recorder(k)
+	}
+	parallel(ctx, n, m.Threads, m.Seed, func(rng *rand.Rand) func(i int) {
+		data := make([]byte, m.Size)
+		return func(i int) { op(ctx, ps, rng, data, i) }
+	})
+	return ps.finish(ctx)
+}
+
+// keys returns the figures reported for a phase, in print order.
+func (m *Metadata) keys(phase string) []stats.Key {
+	size := int64(m.Size)
+	withSizes := func(op trace.Op, keys ...stats.Key) []stats.Key {
+		for _, msize := range m.sizes {
+			if v := m.sizeVariant(msize); v != "" {
+				keys = append(keys, stats.Key{Op: string(op), Variant: v, Size: size})
+			}
+		}
+		return keys
+	}
+	if phase == PhaseLoad {
+		return withSizes(trace.OpPut, stats.Key{Op: string(trace.OpPut), Size: size})
+	}
+	keys := withSizes(trace.OpHead,
+		stats.Key{Op: string(trace.OpHead), Size: size},
+		stats.Key{Op: string(trace.OpHead), Variant: variantExistingKey, Size: size},
+		stats.Key{Op: string(trace.OpHead), Variant: variantMissingKey, Size: size})
+	keys = append(keys, stats.Key{Op: string(trace.OpHeadBucket)})
+	return append(keys, withSizes(trace.OpGet, stats.Key{Op: string(trace.OpGet), Size: size})...)
+}
+
+// metadataKey returns the key of object i of those with metadata size msize.
+func metadataKey(msize, i int) string {
+	return fmt.Sprintf("meta-%d/obj-%d", msize, i)
+}
+
+// sizeVariant returns the variant of calls on objects with msize bytes of
+// metadata, or "" when every object has the same.
+func (m *Metadata) sizeVariant(msize int) string {
+	if len(m.sizes) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("metadata: %d bytes", msize)
+}
+
+// load writes the i-th object, cycling through the metadata sizes.
+func (m *Metadata) load(ctx context.Context, ps *phaseState, rng *rand.Rand, data []byte, i int) {
+	msize := m.sizes[i%len(m.sizes)]
+	key := metadataKey(msize, i/len(m.sizes))
+	md := userMetadata(msize)
+	rng.Read(data)
+	m.issuer().exec(ctx, ps, string(trace.OpPut), []string{m.sizeVariant(msize)}, key, int64(m.Size), int64(m.Size), func(ctx context.Context, b backend.Backend) error {
+		return backend.PutObjectMetadata(ctx, b, m.bucket, key, data, md)
+	})
+}
+
+// inspect issues the i-th call of the metadata phase: the kinds take turns,
+// so each sees the same conditions.
+func (m *Metadata) inspect(ctx context.Context, ps *phaseState, rng *rand.Rand, data []byte, i int) {
+	msize := m.sizes[rng.Intn(len(m.sizes))]
+	key := metadataKey(msize, rng.Intn(m.Objects))
+	size := int64(m.Size)
+	switch i % 4 {
+	case 0:
+		m.issuer().exec(ctx, ps, string(trace.OpHead), []string{variantExistingKey, m.sizeVariant(msize)}, key, size, 0, func(ctx context.Context, b backend.Backend) error {
+			_, err := backend.HeadObject(ctx, b, m.bucket, key)
+			return err
+		})
+	case 1:
+		missing := fmt.Sprintf("missing/obj-%d", i)
+		m.issuer().exec(ctx, ps, string(trace.OpHead), []string{variantMissingKey}, missing, size, 0, func(ctx context.Context, b backend.Backend) error {
+			_, err := backend.HeadObject(ctx, b, m.bucket, missing)
+			if backend.ErrorClass(err) == backend.ClassNotFound {
+				// The answer the call is timed for.
+				return nil
+			}
+			if err == nil {
+				return fmt.Errorf("object %s exists", missing)
+			}
+			return err
+		})
+	case 2:
+		m.issuer().exec(ctx, ps, string(trace.OpHeadBucket), nil, m.bucket, 0, 0, func(ctx context.Context, b backend.Backend) error {
+			return backend.HeadBucket(ctx, b, m.bucket)
+		})
+	case 3:
+		m.issuer().exec(ctx, ps, string(trace.OpGet), []string{m.sizeVariant(msize)}, key, size, size, func(ctx context.Context, b backend.Backend) error {
+			_, err := b.GetObject(ctx, m.bucket, key)
+			return err
+		})
+	}
+}
+
+// issuer returns the issuer of the run's calls.
+func (m *Metadata) issuer() issuer {
+	return m.issuerFor(m.Backend, m.bucket)
+}
+
+// userMetadata returns user metadata taking up n bytes, counting keys and
+// values, split into entries of up to 256 bytes.
+func userMetadata(n int) map[string]string {
+	if n == 0 {
+		return nil
+	}
+	md := map[string]string{}
+	for i := 0; n > 0; i++ {
+		key := fmt.Sprintf("k%d", i)
+		value := strings.Repeat("v", max(0, min(256, n)-len(key)))
+		md[key] = value
+		n -= len(key) + len(value)
+	}
+	return md
+}
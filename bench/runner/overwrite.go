@@ -0,0 +1,597 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// Phases of an overwrite run after the load phase.
+const (
+	PhaseOverwrite   = "overwrite"
+	PhaseConflict    = "conflict"
+	PhaseConditional = "conditional"
+)
+
+// Conditional writes, as reported in results.
+const (
+	OpPutIfNoneMatch = "PUT_IF_NONE_MATCH"
+	OpPutIfMatch     = "PUT_IF_MATCH"
+)
+
+// Variants of conditional writes, by the answer expected of the backend.
+const (
+	variantPreconditionMet    = "precondition met"
+	variantPreconditionFailed = "precondition failed"
+	variantRace               = "race"
+)
+
+// Statuses of a check in the support matrix.
+const (
+	CheckPass        = "pass"
+	CheckFail        = "fail"
+	CheckUnsupported = "unsupported"
+	// CheckSkipped means the run ended before the check got an answer.
+	CheckSkipped = "skipped"
+)
+
+// staleETag is sent with If-Match before an object has had an earlier
+// version; no object has it.
+const staleETag = `"00000000000000000000000000000000"`
+
+// Overwrite measures repeated writes to the same keys: plain overwrites,
+// writers racing on one key, and conditional creates and updates where the
+// backend supports them. Along the way it checks the semantics the backend
+// gives each, and reports them as a support matrix.
+type Overwrite struct {
+	Backend backend.Backend
+	// Size is the size of each object in bytes.
+	Size int
+	// Keys is the number of keys the overwrite phase spreads its writes
+	// over.
+	Keys int
+	// Operations is the number of overwrites, and of conditional writes.
+	Operations int
+	// Writers is the number of writers racing on one key in each round of
+	// the conflict phase and of the conditional races.
+	Writers int
+	// Rounds is the number of those rounds, and of overwrites read back to
+	// check that the last writer wins.
+	Rounds int
+	// Threads is the number of concurrent workers outside the races.
+	Threads int
+	// Seed makes payloads and key choice reproducible.
+	Seed int64
+	Options
+
+	ownedBucket
+	rttMs  float64
+	bucket string
+}
+
+// OverwriteInfo describes an overwrite run in its result.
+type OverwriteInfo struct {
+	ObjectSize int `json:"object_size"`
+	Keys       int `json:"keys"`
+	Operations int `json:"operations"`
+	Writers    int `json:"writers"`
+	Rounds     int `json:"rounds"`
+	Threads    int `json:"threads"`
+}
+
+// Check is one row of the support matrix: a semantic the backend was
+// tested for, and how it did.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// tally counts the trials of a check and those that went as expected. It is
+// safe for concurrent use.
+type tally struct {
+	trials, passed atomic.Int64
+	unsupported    atomic.Bool
+}
+
+// observe counts one trial.
+func (t *tally) observe(ok bool) {
+	t.trials.Add(1)
+	if ok {
+		t.passed.Add(1)
+	}
+}
+
+// check returns the row for t, counting trials as unit.
+func (t *tally) check(name, unit string) Check {
+	trials, passed := t.trials.Load(), t.passed.Load()
+	c := Check{Name: name, Detail: fmt.Sprintf("%d of %d %s as expected", passed, trials, unit)}
+	switch {
+	case trials == 0 && t.unsupported.Load():
+		c.Status, c.Detail = CheckUnsupported, "refused by the backend"
+	case trials == 0:
+		c.Status, c.Detail = CheckSkipped, ""
+	case passed == trials:
+		c.Status = CheckPass
+	default:
+		c.Status = CheckFail
+	}
+	return c
+}
+
+// judge counts a conditional write on t: whether it was written as wanted.
+// Errors other than a refusal say nothing of the backend's semantics and
+// are not counted.
+func judge(t *tally, err error, written, want bool) {
+	switch {
+	case errors.Is(err, backend.ErrUnsupported):
+		t.unsupported.Store(true)
+	case err == nil || errors.Is(err, backend.ErrPreconditionFailed):
+		t.observe(written == want)
+	}
+}
+
+// checks holds a tally per row of the support matrix.
+type checks struct {
+	lastWriter, whole                      tally
+	create, update, createRace, updateRace tally
+}
+
+// matrix returns the support matrix; conditional is false when the backend
+// has no conditional writes to check.
+func (c *checks) matrix(conditional bool) []Check {
+	rows := []Check{
+		c.lastWriter.check("Read after overwrite returns it (last writer wins)", "reads"),
+		c.whole.check("Racing overwrites leave one whole write", "rounds"),
+	}
+	conds := []Check{
+		c.create.check("Conditional create (If-None-Match: *)", "writes"),
+		c.update.check("Conditional update (If-Match)", "writes"),
+		c.createRace.check("Racing conditional creates have one winner", "rounds"),
+		c.updateRace.check("Racing conditional updates have one winner", "rounds"),
+	}
+	for i := range conds {
+		if !conditional {
+			conds[i].Status, conds[i].Detail = CheckUnsupported, "no conditional writes"
+		}
+	}
+	return append(rows, conds...)
+}
+
+// PrintChecks prints the support matrix.
+func PrintChecks(out io.Writer, checks []Check) {
+	fmt.Fprintf(out, "\n===== SUPPORT MATRIX =====\n\n")
+	for _, c := range checks {
+		fmt.Fprintf(out, "%-52s %-12s %s\n", c.Name, c.Status, c.Detail)
+	}
+}
+
+// Scenario names the run in metrics and output files.
+func (o *Overwrite) Scenario() string {
+	return "overwrite"
+}
+
+// Validate reports settings that cannot be run.
+func (o *Overwrite) Validate() error {
+	switch {
+	case o.Size <= 0:
+		// Writes of no bytes could not be told apart.
+		return fmt.Errorf("object size must be positive, got %d", o.Size)
+	case o.Keys <= 0:
+		return fmt.Errorf("keys must be positive, got %d", o.Keys)
+	case o.Operations < 0:
+		return fmt.Errorf("operations must not be negative, got %d", o.Operations)
+	case o.Writers < 2:
+		return fmt.Errorf("writers must be at least 2 to race, got %d", o.Writers)
+	case o.Rounds < 0:
+		return fmt.Errorf("rounds must not be negative, got %d", o.Rounds)
+	case o.Threads <= 0:
+		return fmt.Errorf("threads must be positive, got %d", o.Threads)
+	}
+	return nil
+}
+
+func (o *Overwrite) instruments() instruments {
+	return instruments{
+		out:      o.Out,
+		backend:  o.Backend.Name(),
+		scenario: o.Scenario(),
+		metrics:  o.Metrics,
+		profile:  o.Profile,
+		rttMs:    o.rttMs,
+	}
+}
+
+// issuer returns the issuer of the run's calls.
+func (o *Overwrite) issuer() issuer {
+	return o.issuerFor(o.Backend, o.bucket)
+}
+
+// Run creates a bucket, writes the keys, overwrites them, races writers on
+// one key and makes conditional writes, then prints the metrics of each
+// phase and the support matrix; Close removes the bucket again.
+func (o *Overwrite) Run(ctx context.Context) (*Result, error) {
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.Profile.Check(PhaseLoad, PhaseOverwrite, PhaseConflict, PhaseConditional); err != nil {
+		return nil, err
+	}
+	conditional := backend.SupportsConditionalWrites(o.Backend)
+
+	result := &Result{
+		Backend:  o.Backend.Name(),
+		Scenario: o.Scenario(),
+		Overwrite: &OverwriteInfo{
+			ObjectSize: o.Size,
+			Keys:       o.Keys,
+			Operations: o.Operations,
+			Writers:    o.Writers,
+			Rounds:     o.Rounds,
+			Threads:    o.Threads,
+		},
+		Seed:       o.Seed,
+		Retry:      o.Retry,
+		Timeout:    o.Timeout,
+		DeadlineMs: ms(o.Deadline),
+		StartedAt:  time.Now(),
+	}
+
+	result.Environment = environment.Capture(ctx, o.Backend)
+	result.Environment.Print(o.Out)
+	fmt.Fprintf(o.Out, "Retry policy: %s\n", o.Retry)
+	if conditional && o.Retry.MaxAttempts > 1 {
+		fmt.Fprintf(o.Out, "Conditional writes are not retried\n")
+	}
+	fmt.Fprintf(o.Out, "Operation timeout: %s\n", o.Timeout)
+	if !conditional {
+		fmt.Fprintf(o.Out, "Backend %s has no conditional writes; skipping the conditional phase\n", o.Backend.Name())
+	}
+
+	o.bucket = backend.BucketName(o.Backend, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+	result.Bucket = o.bucket
+	fmt.Fprintf(o.Out, "Creating bucket: %s\n", o.bucket)
+	if err := o.Backend.CreateBucket(ctx, o.bucket); err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+	o.own(ctx, o.Out, o.Cleanup, o.Backend, o.bucket)
+
+	result.Network = probe(ctx, o.Out, o.Probe, o.Backend, o.bucket)
+	o.rttMs = result.Network.RTTMs()
+
+	phaseCtx, cancel := withDeadline(ctx, o.Deadline)
+	defer cancel()
+
+	var c checks
+	capture := o.instruments().startProfile(profile.WholeRun)
+	fmt.Fprintf(o.Out, "\n===== LOAD PHASE =====\n")
+	fmt.Fprintf(o.Out, "\nWriting %d keys of size %d bytes with %d threads\n", o.Keys, o.Size, o.Threads)
+	result.Phases = append(result.Phases, o.load(phaseCtx))
+
+	if phaseCtx.Err() == nil {
+		fmt.Fprintf(o.Out, "\n===== OVERWRITE PHASE =====\n")
+		fmt.Fprintf(o.Out, "\nMaking %d overwrites of %d keys with %d threads, then reading %d overwrites back\n",
+			o.Operations, o.Keys, o.Threads, o.Rounds)
+		result.Phases = append(result.Phases, o.overwrite(phaseCtx, &c.lastWriter))
+	}
+	if phaseCtx.Err() == nil && o.Rounds > 0 {
+		fmt.Fprintf(o.Out, "\n===== CONFLICT PHASE =====\n")
+		fmt.Fprintf(o.Out, "\nRacing %d writers on one key for %d rounds\n", o.Writers, o.Rounds)
+		result.Phases = append(result.Phases, o.conflict(phaseCtx, &c.whole))
+	}
+	if phaseCtx.Err() == nil && conditional {
+		fmt.Fprintf(o.Out, "\n===== CONDITIONAL PHASE =====\n")
+		fmt.Fprintf(o.Out, "\nMaking %d conditional writes with %d threads, then racing %d writers for %d rounds each of creates and updates\n",
+			o.Operations, o.Threads, o.Writers, o.Rounds)
+		result.Phases = append(result.Phases, o.conditional(phaseCtx, &c))
+	}
+	result.Profiles = o.instruments().stopProfile(capture)
+	result.DeadlineReached = deadlineReached(phaseCtx, o.Out, o.Deadline)
+	result.Interrupted = interrupted(ctx, o.Out)
+
+	result.Checks = c.matrix(conditional)
+	PrintChecks(o.Out, result.Checks)
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// overwriteKey returns the k-th key of the load and overwrite phases.
+func overwriteKey(k int) string {
+	return fmt.Sprintf("key-%d", k)
+}
+
+// begin starts a phase whose metrics print in the order of ops.
+func (o *Overwrite) begin(name string, n int, ops ...stats.Key) *phaseState {
+	ps := startPhase(o.instruments(), name, n)
+	for _, k := range ops {
+		ps.set.Recorder(k)
+	}
+	return ps
+}
+
+// put writes data to key, recorded under the plain PUT.
+func (o *Overwrite) put(ctx context.Context, ps *phaseState, key string, data []byte) error {
+	size := int64(o.Size)
+	return o.issuer().exec(ctx, ps, string(trace.OpPut), nil, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		return b.PutObject(ctx, o.bucket, key, data)
+	})
+}
+
+// get reads key back.
+func (o *Overwrite) get(ctx context.Context, ps *phaseState, key string) ([]byte, error) {
+	var data []byte
+	size := int64(o.Size)
+	err := o.issuer().exec(ctx, ps, string(trace.OpGet), nil, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		var err error
+		data, err = b.GetObject(ctx, o.bucket, key)
+		return err
+	})
+	return data, err
+}
+
+// load writes every key once.
+func (o *Overwrite) load(ctx context.Context) PhaseResult {
+	ps := o.begin(PhaseLoad, o.Keys, stats.Key{Op: string(trace.OpPut), Size: int64(o.Size)})
+	parallel(ctx, o.Keys, o.Threads, o.Seed, func(rng *rand.Rand) func(i int) {
+		data := make([]byte, o.Size)
+		return func(i int) {
+			rng.Read(data)
+			o.put(ctx, ps, overwriteKey(i), data)
+		}
+	})
+	return ps.finish(ctx)
+}
+
+// overwrite writes the keys again and again, then overwrites some one at a
+// time and reads each straight back, which must return what was written.
+func (o *Overwrite) overwrite(ctx context.Context, lastWriter *tally) PhaseResult {
+	size := int64(o.Size)
+	ps := o.begin(PhaseOverwrite, o.Operations+2*o.Rounds,
+		stats.Key{Op: string(trace.OpPut), Size: size}, stats.Key{Op: string(trace.OpGet), Size: size})
+	parallel(ctx, o.Operations, o.Threads, o.Seed, func(rng *rand.Rand) func(i int) {
+		data := make([]byte, o.Size)
+		return func(i int) {
+			rng.Read(data)
+			o.put(ctx, ps, overwriteKey(i%o.Keys), data)
+		}
+	})
+
+	rng := rand.New(rand.NewSource(o.Seed))
+	data := make([]byte, o.Size)
+	for r := range o.Rounds {
+		if ctx.Err() != nil {
+			break
+		}
+		key := overwriteKey(r % o.Keys)
+		rng.Read(data)
+		if err := o.put(ctx, ps, key, data); err != nil {
+			continue
+		}
+		if got, err := o.get(ctx, ps, key); err == nil {
+			lastWriter.observe(bytes.Equal(got, data))
+		}
+	}
+	return ps.finish(ctx)
+}
+
+// conflict races the writers on one key each round, then reads the key,
+// which must hold one of the writes whole.
+func (o *Overwrite) conflict(ctx context.Context, whole *tally) PhaseResult {
+	size := int64(o.Size)
+	ps := o.begin(PhaseConflict, o.Rounds*(o.Writers+1),
+		stats.Key{Op: string(trace.OpPut), Size: size}, stats.Key{Op: string(trace.OpGet), Size: size})
+	rng := rand.New(rand.NewSource(o.Seed))
+	versions := make([][]byte, o.Writers)
+	for w := range versions {
+		versions[w] = make([]byte, o.Size)
+	}
+	const key = "conflict"
+	for range o.Rounds {
+		if ctx.Err() != nil {
+			break
+		}
+		for _, v := range versions {
+			rng.Read(v)
+		}
+		written := race(o.Writers, func(w int) bool {
+			return o.put(ctx, ps, key, versions[w]) == nil
+		})
+		if written == 0 {
+			continue
+		}
+		if got, err := o.get(ctx, ps, key); err == nil {
+			whole.observe(slices.ContainsFunc(versions, func(v []byte) bool { return bytes.Equal(v, got) }))
+		}
+	}
+	return ps.finish(ctx)
+}
+
+// conditionalPut makes a conditional write with put, recorded under op.
+// When refusable, a refusal counts as an answer rather than a failure.
+func (o *Overwrite) conditionalPut(ctx context.Context, ps *phaseState, op string, variants []string, key string, refusable bool, put func(ctx context.Context, b backend.Backend) (string, error)) (etag string, written bool, err error) {
+	size := int64(o.Size)
+	// A retry of a write that landed but whose answer was lost would be
+	// refused, and judged as the backend getting it wrong, so conditional
+	// writes get one attempt.
+	is := o.issuer()
+	is.retry.MaxAttempts = 1
+	err = is.exec(ctx, ps, op, variants, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		var err error
+		etag, err = put(ctx, b)
+		written = err == nil
+		if refusable && errors.Is(err, backend.ErrPreconditionFailed) {
+			// The answer the call is timed for.
+			return nil
+		}
+		return err
+	})
+	return etag, written, err
+}
+
+// createIfAbsent makes a conditional create of key with data.
+func (o *Overwrite) createIfAbsent(ctx context.Context, ps *phaseState, variant, key string, refusable bool, data []byte) (string, bool, error) {
+	return o.conditionalPut(ctx, ps, OpPutIfNoneMatch, []string{variant}, key, refusable, func(ctx context.Context, b backend.Backend) (string, error) {
+		return backend.PutObjectIfAbsent(ctx, b, o.bucket, key, data)
+	})
+}
+
+// updateIfMatch makes a conditional update of key, expected to hold etag.
+func (o *Overwrite) updateIfMatch(ctx context.Context, ps *phaseState, variant, key string, refusable bool, data []byte, etag string) (string, bool, error) {
+	return o.conditionalPut(ctx, ps, OpPutIfMatch, []string{variant}, key, refusable, func(ctx context.Context, b backend.Backend) (string, error) {
+		return backend.PutObjectIfMatch(ctx, b, o.bucket, key, data, etag)
+	})
+}
+
+// conditional makes conditional creates of new and existing keys and
+// conditional updates with current and stale ETags, taking turns, then
+// races the writers on conditional creates and updates, each race having
+// exactly one winner.
+func (o *Overwrite) conditional(ctx context.Context, c *checks) PhaseResult {
+	size := int64(o.Size)
+	var keys []stats.Key
+	for _, op := range []string{OpPutIfNoneMatch, OpPutIfMatch} {
+		keys = append(keys, stats.Key{Op: op, Size: size})
+		for _, v := range []string{variantPreconditionMet, variantPreconditionFailed, variantRace} {
+			keys = append(keys, stats.Key{Op: op, Variant: v, Size: size})
+		}
+	}
+	ps := o.begin(PhaseConditional, o.Operations+o.Threads+o.Rounds*(2*o.Writers+1), keys...)
+
+	var workers atomic.Int64
+	parallel(ctx, o.Operations, o.Threads, o.Seed, func(rng *rand.Rand) func(i int) {
+		data := make([]byte, o.Size)
+		// Each worker updates a key of its own, so it knows the current
+		// ETag and the one before.
+		own := fmt.Sprintf("conditional/own-%d", workers.Add(1))
+		rng.Read(data)
+		etag, written, err := o.createIfAbsent(ctx, ps, variantPreconditionMet, own, false, data)
+		judge(&c.create, err, written, true)
+		stale := staleETag
+		return func(i int) {
+			rng.Read(data)
+			switch i % 4 {
+			case 0:
+				key := fmt.Sprintf("conditional/new-%d", i)
+				_, written, err := o.createIfAbsent(ctx, ps, variantPreconditionMet, key, false, data)
+				judge(&c.create, err, written, true)
+			case 1:
+				key := overwriteKey(rng.Intn(o.Keys))
+				_, written, err := o.createIfAbsent(ctx, ps, variantPreconditionFailed, key, true, data)
+				judge(&c.create, err, written, false)
+			case 2:
+				if etag == "" {
+					return
+				}
+				next, written, err := o.updateIfMatch(ctx, ps, variantPreconditionMet, own, false, data, etag)
+				judge(&c.update, err, written, true)
+				if written {
+					stale, etag = etag, next
+				}
+			case 3:
+				if etag == "" {
+					return
+				}
+				next, written, err := o.updateIfMatch(ctx, ps, variantPreconditionFailed, own, true, data, stale)
+				judge(&c.update, err, written, false)
+				if written {
+					etag = next
+				}
+			}
+		}
+	})
+
+	rng := rand.New(rand.NewSource(o.Seed))
+	versions := make([][]byte, o.Writers)
+	for w := range versions {
+		versions[w] = make([]byte, o.Size)
+	}
+	for r := range o.Rounds {
+		if ctx.Err() != nil {
+			break
+		}
+		for _, v := range versions {
+			rng.Read(v)
+		}
+		key := fmt.Sprintf("race/create-%d", r)
+		o.raceRound(&c.createRace, func(w int) (bool, error) {
+			_, written, err := o.createIfAbsent(ctx, ps, variantRace, key, true, versions[w])
+			return written, err
+		})
+
+		key = fmt.Sprintf("race/update-%d", r)
+		etag, _, err := o.createIfAbsent(ctx, ps, "", key, false, versions[0])
+		if err != nil || etag == "" {
+			continue
+		}
+		for _, v := range versions {
+			rng.Read(v)
+		}
+		o.raceRound(&c.updateRace, func(w int) (bool, error) {
+			_, written, err := o.updateIfMatch(ctx, ps, variantRace, key, true, versions[w], etag)
+			return written, err
+		})
+	}
+	return ps.finish(ctx)
+}
+
+// raceRound races the writers through write and counts on t whether exactly
+// one of them won. The round is not counted if any writer failed for a
+// reason other than losing.
+func (o *Overwrite) raceRound(t *tally, write func(w int) (bool, error)) {
+	var failed, unsupported atomic.Bool
+	wins := race(o.Writers, func(w int) bool {
+		written, err := write(w)
+		if errors.Is(err, backend.ErrUnsupported) {
+			unsupported.Store(true)
+		}
+		if err != nil {
+			failed.Store(true)
+		}
+		return written && err == nil
+	})
+	switch {
+	case unsupported.Load():
+		t.unsupported.Store(true)
+	case !failed.Load():
+		t.observe(wins == 1)
+	}
+}
+
+// race calls fn for each of n writers, released at the same moment, and
+// returns how many of the calls returned true.
+func race(n int, fn func(w int) bool) int {
+	start := make(chan struct{})
+	var wins atomic.Int64
+	var wg sync.WaitGroup
+	for w := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if fn(w) {
+				wins.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return int(wins.Load())
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/metrics"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/netprobe"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/resources"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+)
+
+// Options are the settings every scenario shares.
+type Options struct {
+	// Retry decides which failed operations are tried again; the zero
+	// value makes one attempt.
+	Retry retry.Policy
+	// Timeout bounds each operation, retries included; the zero value sets
+	// no timeout.
+	Timeout timeout.Policy
+	// Deadline, when set, bounds the phases. Operations still running when
+	// it is reached are abandoned and not counted.
+	Deadline time.Duration
+	// Out receives progress and metrics; nil means os.Stdout.
+	Out io.Writer
+	// Metrics, when set, receives every operation for the /metrics endpoint.
+	Metrics *metrics.Exporter
+	// Profile, when set, captures profiles of one phase or the whole run.
+	Profile *profile.Plan
+	// Cleanup, when set, tracks the run's bucket so it is removed even if
+	// the program is interrupted.
+	Cleanup *cleanup.Tracker
+	// Probe, when set, measures the network path to the backend before the
+	// first phase, and latencies are also reported net of its RTT.
+	Probe *netprobe.Config
+}
+
+// issuerFor returns the issuer of calls to bucket through b.
+func (o Options) issuerFor(b backend.Backend, bucket string) issuer {
+	return issuer{backend: b, bucket: bucket, retry: o.Retry, timeout: o.Timeout, out: o.Out}
+}
+
+// instruments are the optional observers a run reports its phases to.
+type instruments struct {
+	out      io.Writer
+	backend  string
+	scenario string
+	metrics  *metrics.Exporter
+	profile  *profile.Plan
+	// rttMs is the network round trip measured by the probe, or zero.
+	rttMs float64
+}
+
+// startProfile begins the capture planned for phase, if any.
+func (in instruments) startProfile(phase string) *profile.Capture {
+	c, err := in.profile.Start(in.scenario+"-"+in.backend, phase)
+	if err != nil {
+		fmt.Fprintf(in.out, "Failed to start profiling: %v\n", err)
+	}
+	return c
+}
+
+// stopProfile ends a capture and reports the files it wrote.
+func (in instruments) stopProfile(c *profile.Capture) []string {
+	files, err := c.Stop()
+	if err != nil {
+		fmt.Fprintf(in.out, "Failed to write profiles: %v\n", err)
+	}
+	for _, f := range files {
+		fmt.Fprintf(in.out, "Profile written to %s\n", f)
+	}
+	return files
+}
+
+// phaseState is shared by the workers of one phase.
+type phaseState struct {
+	in      instruments
+	name    string
+	total   int
+	start   time.Time
+	set     *stats.Set
+	tracker *progress.Tracker
+	metrics *metrics.Scope
+	sampler *resources.Sampler
+	capture *profile.Capture
+}
+
+// startPhase begins collecting statistics, progress, client resource usage
+// and any planned profiles for a phase of total operations.
+func startPhase(in instruments, name string, total int) *phaseState {
+	return &phaseState{
+		in:      in,
+		name:    name,
+		total:   total,
+		start:   time.Now(),
+		set:     stats.NewSet(),
+		tracker: progress.StartWriter(in.out, name+" phase", total),
+		metrics: in.metrics.Scope(metrics.Labels{Backend: in.backend, Scenario: in.scenario, Phase: name}),
+		sampler: resources.Start(),
+		capture: in.startProfile(name),
+	}
+}
+
+// finish stops collection, prints the metrics for each operation type and
+// the client resource usage, and returns the phase result. The phase is
+// incomplete if ctx, the one it ran under, ended before it finished.
+func (ps *phaseState) finish(ctx context.Context) PhaseResult {
+	duration := time.Since(ps.start)
+	ps.tracker.Finish()
+	profiles := ps.in.stopProfile(ps.capture)
+
+	res := PhaseResult{
+		Name:       ps.name,
+		DurationMs: ms(duration),
+		Operations: stats.Compact(ps.set.Summaries()),
+		Profiles:   profiles,
+	}
+	// Variants repeat a subset of their operation's samples, so only the
+	// overall summaries count towards the totals.
+	var ops int
+	var bytes int64
+	for _, s := range res.Operations {
+		if s.Variant == "" {
+			ops += s.Count + s.Errors + s.Timeouts
+			bytes += s.Bytes
+		}
+	}
+	usage := ps.sampler.Stop(ops, bytes)
+	res.Resources = &usage
+
+	if ctx.Err() != nil {
+		res.Incomplete = true
+		fmt.Fprintf(ps.in.out, "\nThe %s phase was stopped after %d of %d operations; its results are partial\n", ps.name, ops, ps.total)
+	}
+
+	for _, s := range res.Operations {
+		stats.Print(ps.in.out, s)
+		printNetOfRTT(ps.in.out, s, ps.in.rttMs)
+	}
+	resources.Print(ps.in.out, ps.name, usage)
+	return res
+}
+
+// printNetOfRTT prints the latencies of s less one network round trip, the
+// least any request can take, so the remainder is the storage service's.
+func printNetOfRTT(w io.Writer, s stats.Summary, rttMs float64) {
+	if rttMs <= 0 || s.Count == 0 {
+		return
+	}
+	net := func(v float64) float64 { return max(0, v-rttMs) }
+	fmt.Fprintf(w, "Net of %.2f ms RTT: Average %.2f ms, P50 %.2f ms, P99 %.2f ms\n",
+		rttMs, net(s.AvgMs), net(s.P50Ms), net(s.P99Ms))
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/netprobe"
+)
+
+// PhaseProbe names the network probe in output.
+const PhaseProbe = "probe"
+
+// probeKey is the object the bandwidth probe writes and reads back. It sorts
+// apart from workload keys, so scans never see it.
+const probeKey = "netprobe"
+
+// probe measures the network path to the bucket's endpoint. A probe that
+// fails is reported and the run goes on without a network baseline.
+func probe(ctx context.Context, out io.Writer, cfg *netprobe.Config, b backend.Backend, bucket string) *netprobe.Result {
+	if cfg == nil {
+		return nil
+	}
+	fmt.Fprintf(out, "\n===== PROBE PHASE =====\n")
+	c := *cfg
+	if c.Endpoint == "" {
+		_, c.Endpoint = backend.Location(b, bucket)
+	}
+	if c.Endpoint == "" {
+		fmt.Fprintf(out, "\nSkipping network probe: %s does not report its endpoint\n", b.Name())
+		return nil
+	}
+	res, err := netprobe.Run(ctx, c, netprobe.Transfer{
+		Put: func(ctx context.Context, data []byte) error {
+			return b.PutObject(ctx, bucket, probeKey, data)
+		},
+		Get: func(ctx context.Context) error {
+			_, err := b.GetObject(ctx, bucket, probeKey)
+			return err
+		},
+	})
+	// A probe object left by a failed transfer goes with the bucket.
+	if res != nil && res.TransferBytes > 0 {
+		if err := b.DeleteObject(ctx, bucket, probeKey); err != nil {
+			fmt.Fprintf(out, "Failed to delete probe object: %v\n", err)
+		}
+	}
+	if res != nil {
+		res.Print(out)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "Network probe failed: %v\n", err)
+	}
+	return res
+}
@@ -0,0 +1,299 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/payload"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+)
+
+// Phase names used by trace replays.
+const (
+	PhasePrepare = "prepare"
+	PhaseReplay  = "replay"
+)
+
+// Replay reissues the operations of a trace against a backend. All traced
+// buckets are mapped into one fresh bucket, with the original bucket name as
+// a key prefix, and PUTs carry synthetic payloads of the logged size.
+type Replay struct {
+	Backend backend.Backend
+	Records []trace.Record
+	// Source names the trace in the result.
+	Source string
+	// Failed is the number of records left out of Records because they
+	// failed when they were logged, for the result.
+	Failed int
+	// Speed scales the gaps between records: 1 keeps the original timing,
+	// 10 replays ten times faster, and 0 issues records back to back.
+	Speed float64
+	// Concurrency caps the number of operations in flight.
+	Concurrency int
+	// Seed makes the synthetic payloads reproducible.
+	Seed int64
+	// Payload generates the data PUTs carry; nil sends random data.
+	Payload *payload.Generator
+	Options
+
+	ownedBucket
+	rttMs   float64
+	bucket  string
+	payload []byte
+}
+
+// Scenario names the replay in metrics and output files after its trace,
+// e.g. "replay-access" for access.log.
+func (rp *Replay) Scenario() string {
+	base := filepath.Base(rp.Source)
+	return "replay-" + strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Run prepares the objects the trace reads before writing, replays every
+// record and prints per-operation metrics; Close removes the bucket again.
+func (rp *Replay) Run(ctx context.Context) (*Result, error) {
+	if rp.Out == nil {
+		rp.Out = os.Stdout
+	}
+	if len(rp.Records) == 0 {
+		return nil, fmt.Errorf("trace %s has no replayable records", rp.Source)
+	}
+	if rp.Concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", rp.Concurrency)
+	}
+	if rp.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative, got %g", rp.Speed)
+	}
+	if err := rp.Profile.Check(PhasePrepare, PhaseReplay); err != nil {
+		return nil, err
+	}
+
+	info := &TraceInfo{Source: rp.Source, Records: len(rp.Records), Speed: rp.Speed, Skipped: map[string]int{}, Failed: rp.Failed}
+	result := &Result{
+		Backend:    rp.Backend.Name(),
+		Scenario:   rp.Scenario(),
+		Trace:      info,
+		Seed:       rp.Seed,
+		Retry:      rp.Retry,
+		Timeout:    rp.Timeout,
+		DeadlineMs: ms(rp.Deadline),
+		StartedAt:  time.Now(),
+	}
+
+	// One buffer serves every payload; only its compressibility matters.
+	var maxSize int64
+	for _, rec := range rp.Records {
+		if rec.Size > maxSize {
+			maxSize = rec.Size
+		}
+	}
+	rp.payload = make([]byte, maxSize)
+	rng := rand.New(rand.NewSource(rp.Seed))
+	if rp.Payload != nil {
+		rp.Payload.Fill(rng, rp.payload)
+		result.Payloads = []payload.Info{rp.Payload.Info()}
+	} else {
+		rng.Read(rp.payload)
+	}
+
+	result.Environment = environment.Capture(ctx, rp.Backend)
+	result.Environment.Print(rp.Out)
+	fmt.Fprintf(rp.Out, "Retry policy: %s\n", rp.Retry)
+	fmt.Fprintf(rp.Out, "Operation timeout: %s\n", rp.Timeout)
+	if rp.Payload != nil {
+		fmt.Fprintf(rp.Out, "Payload: %s\n", rp.Payload)
+	}
+
+	rp.bucket = backend.BucketName(rp.Backend, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+	result.Bucket = rp.bucket
+	fmt.Fprintf(rp.Out, "Creating bucket: %s\n", rp.bucket)
+	if err := rp.Backend.CreateBucket(ctx, rp.bucket); err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+	rp.own(ctx, rp.Out, rp.Cleanup, rp.Backend, rp.bucket)
+
+	result.Network = probe(ctx, rp.Out, rp.Probe, rp.Backend, rp.bucket)
+	rp.rttMs = result.Network.RTTMs()
+
+	phaseCtx, cancel := withDeadline(ctx, rp.Deadline)
+	defer cancel()
+
+	capture := rp.instruments().startProfile(profile.WholeRun)
+	fmt.Fprintf(rp.Out, "\n===== PREPARE PHASE =====\n")
+	result.Phases = append(result.Phases, rp.prepare(phaseCtx))
+
+	if phaseCtx.Err() == nil {
+		fmt.Fprintf(rp.Out, "\n===== REPLAY PHASE =====\n")
+		fmt.Fprintf(rp.Out, "\nReplaying %d records at speed %g with concurrency %d\n", len(rp.Records), rp.Speed, rp.Concurrency)
+		result.Phases = append(result.Phases, rp.replay(phaseCtx, info))
+	}
+	result.Profiles = rp.instruments().stopProfile(capture)
+	result.DeadlineReached = deadlineReached(phaseCtx, rp.Out, rp.Deadline)
+	result.Interrupted = interrupted(ctx, rp.Out)
+
+	for op, n := range info.Skipped {
+		fmt.Fprintf(rp.Out, "Skipped %d %s records not supported by %s\n", n, op, rp.Backend.Name())
+	}
+	if info.MaxLagMs > 0 {
+		fmt.Fprintf(rp.Out, "Replay fell behind the trace schedule by up to %.2f ms\n", info.MaxLagMs)
+	}
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// objectKey maps a traced object into the replay bucket.
+func objectKey(rec trace.Record) string {
+	if rec.Bucket == "" {
+		return rec.Key
+	}
+	return rec.Bucket + "/" + rec.Key
+}
+
+// prepare writes every object that the trace reads before it writes, so
+// those reads find an object of the logged size.
+func (rp *Replay) prepare(ctx context.Context) PhaseResult {
+	seeds := trace.Seeds(rp.Records)
+	fmt.Fprintf(rp.Out, "\nPreparing %d objects read before being written\n", len(seeds))
+
+	ps := startPhase(rp.instruments(), PhasePrepare, len(seeds))
+	for _, rec := range seeds {
+		if ctx.Err() != nil {
+			break
+		}
+		rp.exec(ctx, ps, rec)
+	}
+	return ps.finish(ctx)
+}
+
+// replay issues every record on the trace's schedule. Records for the same
+// key are issued in trace order even when they overlap, so a GET never
+// overtakes the PUT that creates its object.
+func (rp *Replay) replay(ctx context.Context, info *TraceInfo) PhaseResult {
+	total := 0
+	for _, rec := range rp.Records {
+		if rp.supported(rec.Op) {
+			total++
+		}
+	}
+	ps := startPhase(rp.instruments(), PhaseReplay, total)
+	first := rp.Records[0].Time
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, rp.Concurrency)
+	lastDone := make(map[string]chan struct{})
+	for _, rec := range rp.Records {
+		if !rp.supported(rec.Op) {
+			info.Skipped[string(rec.Op)]++
+			continue
+		}
+		// Create recorders in trace order so metrics print in that order.
+		ps.set.Recorder(stats.Key{Op: string(rec.Op)})
+
+		due := ps.start
+		if rp.Speed > 0 {
+			due = ps.start.Add(time.Duration(float64(rec.Time.Sub(first)) / rp.Speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		if rp.Speed > 0 {
+			if lag := ms(time.Since(due)); lag > info.MaxLagMs {
+				info.MaxLagMs = lag
+			}
+		}
+
+		key := objectKey(rec)
+		prev, done := lastDone[key], make(chan struct{})
+		lastDone[key] = done
+
+		wg.Add(1)
+		go func(rec trace.Record) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer close(done)
+			if prev != nil {
+				<-prev
+			}
+			rp.exec(ctx, ps, rec)
+		}(rec)
+	}
+	wg.Wait()
+
+	return ps.finish(ctx)
+}
+
+// supported reports whether op can be replayed on the backend.
+func (rp *Replay) supported(op trace.Op) bool {
+	switch op {
+	case trace.OpGet, trace.OpPut, trace.OpDelete, trace.OpList:
+		return true
+	case trace.OpHead, trace.OpHeadBucket:
+		_, ok := rp.Backend.(backend.Inspector)
+		return ok
+	}
+	return false
+}
+
+// exec issues one record and records its latency under the record's op.
+func (rp *Replay) exec(ctx context.Context, ps *phaseState, rec trace.Record) {
+	key := objectKey(rec)
+	var bytes int64
+	if rec.Op == trace.OpGet || rec.Op == trace.OpPut {
+		bytes = rec.Size
+	}
+	is := rp.issuerFor(rp.Backend, rp.bucket)
+	is.unsized = true
+	is.exec(ctx, ps, string(rec.Op), nil, key, rec.Size, bytes, func(ctx context.Context, b backend.Backend) error {
+		switch rec.Op {
+		case trace.OpGet:
+			_, err := b.GetObject(ctx, rp.bucket, key)
+			return err
+		case trace.OpPut:
+			return b.PutObject(ctx, rp.bucket, key, rp.payload[:rec.Size])
+		case trace.OpDelete:
+			return b.DeleteObject(ctx, rp.bucket, key)
+		case trace.OpList:
+			_, err := b.ListObjects(ctx, rp.bucket, key)
+			return err
+		case trace.OpHead:
+			_, err := backend.HeadObject(ctx, b, rp.bucket, key)
+			return err
+		case trace.OpHeadBucket:
+			return backend.HeadBucket(ctx, b, rp.bucket)
+		}
+		return nil
+	})
+}
+
+func (rp *Replay) instruments() instruments {
+	return instruments{
+		out:      rp.Out,
+		backend:  rp.Backend.Name(),
+		scenario: rp.Scenario(),
+		metrics:  rp.Metrics,
+		profile:  rp.Profile,
+		rttMs:    rp.rttMs,
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cost"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/netprobe"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/payload"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/resources"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/trace"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// Result is the structured record of one run, written with --out. Workload
+// is set for generated workloads, Trace for trace replays, and Metadata,
+// Copy and Overwrite for those scenarios.
+type Result struct {
+	Backend   string            `json:"backend"`
+	Bucket    string            `json:"bucket"`
+	Scenario  string            `json:"scenario"`
+	Workload  workload.Workload `json:"workload,omitzero"`
+	Trace     *TraceInfo        `json:"trace,omitempty"`
+	Metadata  *MetadataInfo     `json:"metadata,omitempty"`
+	Copy      *CopyInfo         `json:"copy,omitempty"`
+	Overwrite *OverwriteInfo    `json:"overwrite,omitempty"`
+	Seed      int64             `json:"seed"`
+	Retry     retry.Policy      `json:"retry,omitzero"`
+	Timeout   timeout.Policy    `json:"timeout,omitzero"`
+	// Deadline is the run deadline in milliseconds, and DeadlineReached
+	// is set if it cut the run short.
+	DeadlineMs      float64 `json:"deadline_ms,omitempty"`
+	DeadlineReached bool    `json:"deadline_reached,omitempty"`
+	// Interrupted is set if a signal stopped the run before it finished.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Cold is set when every run-phase operation used a fresh client.
+	Cold bool `json:"cold,omitempty"`
+	// ColdUnmeasured lists the cold-start variants the backend's clients
+	// give no separate figure for; their time falls under client
+	// construction or the request.
+	ColdUnmeasured []string `json:"cold_unmeasured,omitempty"`
+	// Payloads describes the data written, when it was not left random.
+	Payloads []payload.Info `json:"payloads,omitempty"`
+	// Agents is the number of agents a distributed run was merged from.
+	Agents int `json:"agents,omitempty"`
+	// Environment describes the host the run was driven from, and
+	// AgentEnvironments each agent of a distributed run.
+	Environment       *environment.Info   `json:"environment,omitempty"`
+	AgentEnvironments []*environment.Info `json:"agent_environments,omitempty"`
+	// Network is the baseline measured by the probe before the run.
+	Network    *netprobe.Result `json:"network,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Phases     []PhaseResult    `json:"phases"`
+	// Profiles lists the files captured over the whole run.
+	Profiles []string `json:"profiles,omitempty"`
+	// Checks is the support matrix of an overwrite run.
+	Checks []Check `json:"checks,omitempty"`
+	// Cost prices the run with the backend's pricing model, if it has one.
+	Cost *cost.Report `json:"cost,omitempty"`
+}
+
+// TraceInfo describes the trace a replay result was produced from.
+type TraceInfo struct {
+	Source  string  `json:"source"`
+	Records int     `json:"records"`
+	Speed   float64 `json:"speed"`
+	// Skipped counts records per operation that the backend cannot replay.
+	Skipped map[string]int `json:"skipped,omitempty"`
+	// Failed counts records left out because they failed when logged.
+	Failed int `json:"failed,omitempty"`
+	// MaxLagMs is how far the replay fell behind the trace's schedule.
+	MaxLagMs float64 `json:"max_lag_ms"`
+}
+
+// PhaseResult holds the metrics for every operation type in one phase, and
+// the client's resource usage while it ran.
+type PhaseResult struct {
+	Name       string           `json:"name"`
+	DurationMs float64          `json:"duration_ms"`
+	Operations []stats.Summary  `json:"operations"`
+	Resources  *resources.Usage `json:"resources,omitempty"`
+	// Profiles lists the profile and trace files captured for the phase.
+	Profiles []string `json:"profiles,omitempty"`
+	// Incomplete is set if the run deadline or a signal stopped the phase
+	// before it issued all its operations.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// WriteFile writes the result as indented JSON to path.
+func (res *Result) WriteFile(path string) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// Usage returns what the run did that the backend bills for, counted from
+// its phases. Failed operations and retried attempts count as requests,
+// since services bill them too. Replay results record no per-operation size, so their transfers use
+// the average size of each operation.
+func (res *Result) Usage() cost.Usage {
+	var u cost.Usage
+	var objects int64
+	for _, ph := range res.Phases {
+		for _, s := range ph.Operations {
+			if s.Variant != "" {
+				continue
+			}
+			size := s.Size
+			if size == 0 && s.Count > 0 {
+				size = s.Bytes / int64(s.Count)
+			}
+			requests := s.Count + s.Errors + s.Timeouts
+			if s.Attempts > 0 {
+				requests = s.Attempts
+			}
+			switch s.Op {
+			case OpCopy, OpRename:
+				method, partSize := res.Copy.method(s.Size)
+				u.AddCopy(method, int64(requests), s.Size, partSize)
+			case OpPutIfNoneMatch, OpPutIfMatch:
+				u.AddOp(string(trace.OpPut), int64(requests), size)
+			default:
+				u.AddOp(s.Op, int64(requests), size)
+			}
+			switch s.Op {
+			case string(workload.OpInsert), string(trace.OpPut):
+				objects += int64(s.Count)
+				u.StoredBytes += s.Bytes
+			case OpCopy:
+				objects += int64(s.Count)
+				u.StoredBytes += s.Bytes
+			case OpRename:
+				u.Add(cost.RequestDelete, int64(requests), 0)
+			}
+		}
+	}
+	if n := res.Network; n != nil && n.TransferBytes > 0 {
+		u.AddProbe(netprobe.Transfers, int64(n.TransferBytes))
+	}
+	agents := max(1, res.Agents)
+	for range agents {
+		u.AddBucket(objects / int64(agents))
+	}
+	u.Duration = res.FinishedAt.Sub(res.StartedAt)
+	return u
+}
@@ -0,0 +1,410 @@
+// Package runner executes workloads against a storage backend and collects
+// per-operation statistics.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/environment"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/payload"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/profile"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// Phase names used in results.
+const (
+	PhaseLoad = "load"
+	PhaseRun  = "run"
+)
+
+// Runner executes a workload against a backend in two phases: a load phase
+// that inserts RecordCount objects, and a run phase that issues
+// OperationCount operations drawn from the workload mix.
+type Runner struct {
+	Backend  backend.Backend
+	Workload workload.Workload
+	// Seed makes key choice and payloads reproducible across runs.
+	Seed int64
+	Options
+	// Barrier, when set, is called before each phase starts and may block;
+	// distributed agents use it to start every phase together.
+	Barrier func(ctx context.Context, phase string) error
+	// OnPhase, when set, is called as each phase starts with the set its
+	// statistics are recorded in, so they can be streamed while it runs.
+	OnPhase func(phase string, set *stats.Set)
+	// NewClient, when set, opens a fresh client for every run-phase
+	// operation, and the latency of each is broken down into client
+	// construction, credential resolution, connection setup and request.
+	NewClient func(ctx context.Context) (backend.Backend, error)
+	// Bucket, when set, is an existing bucket the run uses instead of
+	// creating one, and leaves in place afterwards.
+	Bucket string
+	// Preloaded skips the load phase, because Bucket already holds the
+	// workload's records from an earlier run.
+	Preloaded bool
+	// Payloads generate the data inserts and updates write, taking turns
+	// by record; nil writes random data. With more than one, operations
+	// on a record are also reported under the payload it holds.
+	Payloads []*payload.Generator
+
+	ownedBucket
+	rttMs   float64
+	bucket  string
+	keys    *workload.KeySpace
+	chooser workload.Chooser
+	phases  int
+	// seen holds the records read at least once, to split first from
+	// repeat accesses.
+	seen sync.Map
+}
+
+// Scenario names the run in metrics and output files, e.g. "preset-b".
+func (r *Runner) Scenario() string {
+	return PresetScenario(r.Workload)
+}
+
+// PresetScenario returns the scenario name of a run of w.
+func PresetScenario(w workload.Workload) string {
+	return "preset-" + strings.ToLower(w.Name)
+}
+
+func (r *Runner) instruments() instruments {
+	return instruments{
+		out:      r.Out,
+		backend:  r.Backend.Name(),
+		scenario: r.Scenario(),
+		metrics:  r.Metrics,
+		profile:  r.Profile,
+		rttMs:    r.rttMs,
+	}
+}
+
+// Run creates a bucket, executes both phases and prints their metrics; Close
+// removes the bucket again. With Bucket set it uses that bucket instead and
+// leaves it in place, and with Preloaded it skips the load phase.
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	if r.Out == nil {
+		r.Out = os.Stdout
+	}
+	w := r.Workload
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.Profile.Check(PhaseLoad, PhaseRun); err != nil {
+		return nil, err
+	}
+	chooser, err := workload.NewChooser(w)
+	if err != nil {
+		return nil, err
+	}
+	r.chooser = chooser
+	r.keys = workload.NewKeySpace()
+	if r.Preloaded {
+		if r.Bucket == "" {
+			return nil, fmt.Errorf("a preloaded run needs a bucket")
+		}
+		r.keys = workload.NewKeySpaceAt(int64(w.RecordCount))
+	}
+
+	result := &Result{
+		Backend:    r.Backend.Name(),
+		Scenario:   r.Scenario(),
+		Workload:   w,
+		Seed:       r.Seed,
+		Retry:      r.Retry,
+		Timeout:    r.Timeout,
+		DeadlineMs: ms(r.Deadline),
+		Cold:       r.NewClient != nil,
+		StartedAt:  time.Now(),
+	}
+
+	result.Environment = environment.Capture(ctx, r.Backend)
+	result.Environment.Print(r.Out)
+	fmt.Fprintf(r.Out, "Retry policy: %s\n", r.Retry)
+	fmt.Fprintf(r.Out, "Operation timeout: %s\n", r.Timeout)
+	if r.NewClient != nil {
+		result.ColdUnmeasured = coldUnmeasured(r.Backend)
+		if len(result.ColdUnmeasured) > 0 {
+			fmt.Fprintf(r.Out, "Not measured separately on %s: %s\n", r.Backend.Name(), strings.Join(result.ColdUnmeasured, ", "))
+		}
+	}
+	for _, g := range r.Payloads {
+		fmt.Fprintf(r.Out, "Payload: %s\n", g)
+		result.Payloads = append(result.Payloads, g.Info())
+	}
+
+	if r.Bucket != "" {
+		r.bucket = r.Bucket
+		fmt.Fprintf(r.Out, "Using bucket: %s\n", r.bucket)
+	} else {
+		// Create a unique bucket for testing
+		r.bucket = backend.BucketName(r.Backend, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+		fmt.Fprintf(r.Out, "Creating bucket: %s\n", r.bucket)
+		if err := r.Backend.CreateBucket(ctx, r.bucket); err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+		r.own(ctx, r.Out, r.Cleanup, r.Backend, r.bucket)
+	}
+	result.Bucket = r.bucket
+
+	result.Network = probe(ctx, r.Out, r.Probe, r.Backend, r.bucket)
+	r.rttMs = result.Network.RTTMs()
+
+	phaseCtx, cancel := withDeadline(ctx, r.Deadline)
+	defer cancel()
+
+	capture := r.instruments().startProfile(profile.WholeRun)
+	if err := r.barrier(ctx, PhaseLoad); err != nil {
+		return nil, err
+	}
+	if r.Preloaded {
+		fmt.Fprintf(r.Out, "\nSkipping the load phase: the bucket already holds %d objects\n", w.RecordCount)
+	} else {
+		fmt.Fprintf(r.Out, "\n===== LOAD PHASE =====\n")
+		fmt.Fprintf(r.Out, "\nInserting %d objects of size %d bytes with %d threads\n", w.RecordCount, w.ObjectSize, w.Threads)
+		result.Phases = append(result.Phases, r.phase(phaseCtx, PhaseLoad, w.RecordCount, r.loadOp))
+	}
+
+	if w.OperationCount > 0 && phaseCtx.Err() == nil {
+		if err := r.barrier(ctx, PhaseRun); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(r.Out, "\n===== RUN PHASE =====\n")
+		fmt.Fprintf(r.Out, "\nRunning %d operations with %d threads\n", w.OperationCount, w.Threads)
+		result.Phases = append(result.Phases, r.phase(phaseCtx, PhaseRun, w.OperationCount, r.runOp))
+	}
+	result.Profiles = r.instruments().stopProfile(capture)
+	result.DeadlineReached = deadlineReached(phaseCtx, r.Out, r.Deadline)
+	result.Interrupted = interrupted(ctx, r.Out)
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// barrier waits until the phase may start.
+func (r *Runner) barrier(ctx context.Context, phase string) error {
+	if r.Barrier == nil {
+		return nil
+	}
+	if err := r.Barrier(ctx, phase); err != nil {
+		return fmt.Errorf("%s phase barrier: %w", phase, err)
+	}
+	return nil
+}
+
+// worker holds the per-goroutine state an operation needs.
+type worker struct {
+	rng  *rand.Rand
+	data []byte
+}
+
+// payload fills the worker's buffer with fresh data for record i.
+func (r *Runner) payload(wk *worker, i int64) []byte {
+	if len(r.Payloads) == 0 {
+		wk.rng.Read(wk.data)
+	} else {
+		r.Payloads[i%int64(len(r.Payloads))].Fill(wk.rng, wk.data)
+	}
+	return wk.data
+}
+
+// payloadVariant returns the variant operations on record i are also
+// reported under, or "" when every record holds the same payload.
+func (r *Runner) payloadVariant(i int64) string {
+	if len(r.Payloads) < 2 {
+		return ""
+	}
+	return "payload: " + r.Payloads[i%int64(len(r.Payloads))].Name()
+}
+
+type opFunc func(ctx context.Context, wk *worker, ps *phaseState)
+
+// phase runs n operations spread over the workload's threads and prints the
+// metrics for each operation type and the client resource usage.
+func (r *Runner) phase(ctx context.Context, name string, n int, op opFunc) PhaseResult {
+	ps := startPhase(r.instruments(), name, n)
+	if r.OnPhase != nil {
+		r.OnPhase(name, ps.set)
+	}
+	r.phases++
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for t := 0; t < r.Workload.Threads; t++ {
+		wk := &worker{
+			rng:  rand.New(rand.NewSource(r.Seed + int64(r.phases)<<32 + int64(t))),
+			data: make([]byte, r.Workload.ObjectSize),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next.Add(1) <= int64(n) {
+				if ctx.Err() != nil {
+					return
+				}
+				op(ctx, wk, ps)
+			}
+		}()
+	}
+	wg.Wait()
+	return ps.finish(ctx)
+}
+
+// Read variants reported alongside the overall read metrics.
+const (
+	variantFirstAccess  = "first access"
+	variantRepeatAccess = "repeat access"
+)
+
+// timed runs fn against target and records its latency under op, and also
+// under each non-empty variant of op given. fn is handed the client to use:
+// the shared one, or a fresh one per run-phase operation when r.NewClient is
+// set.
+func (r *Runner) timed(ctx context.Context, ps *phaseState, op workload.Op, variants []string, target string, size, bytes int64, fn func(ctx context.Context, b backend.Backend) error) error {
+	is := r.issuerFor(r.Backend, r.bucket)
+	if ps.name == PhaseRun {
+		is.newClient = r.NewClient
+	}
+	return is.exec(ctx, ps, string(op), variants, target, size, bytes, fn)
+}
+
+// recordAttempts adds the attempts an operation took to rec, and the
+// latency of its first attempt under the first key. An operation that timed
+// out after one attempt timed out on that attempt.
+func recordAttempts(ps *phaseState, rec *stats.Recorder, first stats.Key, start time.Time, a retry.Attempts, bytes int64, timedOut bool) {
+	if a.Count == 0 {
+		return
+	}
+	rec.RecordAttempts(a.Count, a.Retrying)
+	if a.FirstErr != nil {
+		bytes = 0
+	}
+	record(ps.set.Recorder(first), start, a.First, bytes, a.FirstErr, timedOut && a.Count == 1)
+}
+
+func (r *Runner) loadOp(ctx context.Context, wk *worker, ps *phaseState) {
+	r.insert(ctx, wk, ps)
+}
+
+func (r *Runner) runOp(ctx context.Context, wk *worker, ps *phaseState) {
+	switch r.Workload.Mix.Choose(wk.rng) {
+	case workload.OpRead:
+		r.read(ctx, wk, ps)
+	case workload.OpUpdate:
+		r.update(ctx, wk, ps)
+	case workload.OpInsert:
+		r.insert(ctx, wk, ps)
+	case workload.OpScan:
+		r.scan(ctx, wk, ps)
+	case workload.OpReadModifyWrite:
+		r.readModifyWrite(ctx, wk, ps)
+	}
+}
+
+// pick chooses an existing record according to the workload distribution.
+func (r *Runner) pick(wk *worker) int64 {
+	return r.chooser.Next(wk.rng, r.keys.Limit())
+}
+
+func (r *Runner) insert(ctx context.Context, wk *worker, ps *phaseState) {
+	i := r.keys.Allocate()
+	defer r.keys.Ack(i)
+	size := int64(r.Workload.ObjectSize)
+	key := workload.Key(i)
+	data := r.payload(wk, i)
+	r.timed(ctx, ps, workload.OpInsert, []string{r.payloadVariant(i)}, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		return b.PutObject(ctx, r.bucket, key, data)
+	})
+}
+
+// read fetches a record and reports whether this was the first time the run
+// read it, which exposes server-side caching.
+func (r *Runner) read(ctx context.Context, wk *worker, ps *phaseState) {
+	i := r.pick(wk)
+	key := workload.Key(i)
+	size := int64(r.Workload.ObjectSize)
+	variant := variantFirstAccess
+	if _, repeat := r.seen.LoadOrStore(i, struct{}{}); repeat {
+		variant = variantRepeatAccess
+	}
+	r.timed(ctx, ps, workload.OpRead, []string{variant, r.payloadVariant(i)}, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		_, err := b.GetObject(ctx, r.bucket, key)
+		return err
+	})
+}
+
+func (r *Runner) update(ctx context.Context, wk *worker, ps *phaseState) {
+	i := r.pick(wk)
+	key := workload.Key(i)
+	size := int64(r.Workload.ObjectSize)
+	data := r.payload(wk, i)
+	r.timed(ctx, ps, workload.OpUpdate, []string{r.payloadVariant(i)}, key, size, size, func(ctx context.Context, b backend.Backend) error {
+		return b.PutObject(ctx, r.bucket, key, data)
+	})
+}
+
+func (r *Runner) scan(ctx context.Context, wk *worker, ps *phaseState) {
+	prefix := workload.ScanPrefix(r.pick(wk))
+	r.timed(ctx, ps, workload.OpScan, nil, prefix, 0, 0, func(ctx context.Context, b backend.Backend) error {
+		_, err := b.ListObjects(ctx, r.bucket, prefix)
+		return err
+	})
+}
+
+func (r *Runner) readModifyWrite(ctx context.Context, wk *worker, ps *phaseState) {
+	i := r.pick(wk)
+	key := workload.Key(i)
+	size := int64(r.Workload.ObjectSize)
+	r.timed(ctx, ps, workload.OpReadModifyWrite, []string{r.payloadVariant(i)}, key, size, 2*size, func(ctx context.Context, b backend.Backend) error {
+		if _, err := b.GetObject(ctx, r.bucket, key); err != nil {
+			return err
+		}
+		return b.PutObject(ctx, r.bucket, key, r.payload(wk, i))
+	})
+}
+
+// ownedBucket holds the bucket a run created until the caller removes it
+// with Close, once the result is saved.
+type ownedBucket struct {
+	out    io.Writer
+	bucket *cleanup.Resource
+}
+
+// own marks a bucket the run created with the ownership marker, so
+// "bench sweep" can find it if cleanup fails, and tracks it with t so it is
+// removed on exit.
+func (o *ownedBucket) own(ctx context.Context, out io.Writer, t *cleanup.Tracker, b backend.Backend, bucket string) {
+	if err := backend.MarkOwned(ctx, b, bucket); err != nil {
+		fmt.Fprintf(out, "Failed to mark bucket as benchmark-owned: %v\n", err)
+	}
+	o.out = out
+	o.bucket = t.Track(cleanup.Bucket, bucket, func(ctx context.Context) error {
+		return backend.RemoveBucket(ctx, b, bucket)
+	})
+}
+
+// Close empties and deletes the bucket Run created, if any, reporting
+// failures to the run's output. Defer it before Run, so the result is
+// written before cleanup begins.
+func (o *ownedBucket) Close(ctx context.Context) {
+	if o.bucket == nil {
+		return
+	}
+	fmt.Fprintf(o.out, "\nCleaning up bucket: %s\n", o.bucket.Name)
+	if err := o.bucket.Remove(ctx); err != nil {
+		fmt.Fprintf(o.out, "Failed to clean up bucket: %v\n", err)
+	}
+	o.bucket = nil
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/stats"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+)
+
+// withDeadline returns the context the phases of a run execute under, which
+// ends with timeout.ErrDeadline as its cause after d. A zero d sets no
+// deadline.
+func withDeadline(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, timeout.ErrDeadline)
+}
+
+// deadlineReached reports whether the phases under ctx were cut short by
+// the run deadline, and says so on out.
+func deadlineReached(ctx context.Context, out io.Writer, d time.Duration) bool {
+	if !errors.Is(context.Cause(ctx), timeout.ErrDeadline) {
+		return false
+	}
+	fmt.Fprintf(out, "\nRun deadline of %s reached: the results cover the operations completed before it\n", d)
+	return true
+}
+
+// interrupted reports whether the run under ctx was stopped by a signal,
+// and says so on out.
+func interrupted(ctx context.Context, out io.Writer) bool {
+	if !cleanup.Interrupted(ctx) {
+		return false
+	}
+	fmt.Fprintf(out, "\nRun interrupted: the results cover the operations completed before it was stopped\n")
+	return true
+}
+
+// record adds the outcome of one operation to rec: a latency sample, an
+// error, or a timeout when timedOut is set.
+func record(rec *stats.Recorder, start time.Time, latency time.Duration, bytes int64, err error, timedOut bool) {
+	if timedOut {
+		rec.RecordTimeout(start, latency)
+		return
+	}
+	rec.Record(start, latency, bytes, err)
+}
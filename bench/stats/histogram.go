@@ -0,0 +1,229 @@
+package stats
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// subBucketBits sets the histogram resolution: each power of two of
+// nanoseconds is split into 1<<subBucketBits linear buckets, which bounds the
+// relative error of a reported percentile to about 1.6%.
+const subBucketBits = 6
+
+const subBuckets = 1 << subBucketBits
+
+// Histogram is a mergeable latency histogram with log-linear buckets. Unlike
+// a Recorder it has a fixed size, so histograms from many processes can be
+// shipped and combined.
+type Histogram struct {
+	Counts map[int]uint64 `json:"counts"`
+	Total  uint64         `json:"total"`
+	SumNs  int64          `json:"sum_ns"`
+	MinNs  int64          `json:"min_ns"`
+	MaxNs  int64          `json:"max_ns"`
+}
+
+// bucketOf returns the bucket index of a latency in nanoseconds.
+func bucketOf(ns int64) int {
+	if ns < subBuckets {
+		return int(max(ns, 0))
+	}
+	exp := bits.Len64(uint64(ns)) - 1
+	sub := int(ns>>(exp-subBucketBits)) & (subBuckets - 1)
+	return (exp-subBucketBits+1)*subBuckets + sub
+}
+
+// bucketRange returns the lowest value and width of bucket i.
+func bucketRange(i int) (low, width int64) {
+	if i < subBuckets {
+		return int64(i), 1
+	}
+	exp := i/subBuckets + subBucketBits - 1
+	sub := int64(i % subBuckets)
+	width = int64(1) << (exp - subBucketBits)
+	return (subBuckets + sub) * width, width
+}
+
+// Record adds one latency.
+func (h *Histogram) Record(d time.Duration) {
+	ns := d.Nanoseconds()
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64)
+	}
+	if h.Total == 0 || ns < h.MinNs {
+		h.MinNs = ns
+	}
+	if ns > h.MaxNs {
+		h.MaxNs = ns
+	}
+	h.Counts[bucketOf(ns)]++
+	h.Total++
+	h.SumNs += ns
+}
+
+// Merge adds every sample of o to h.
+func (h *Histogram) Merge(o Histogram) {
+	if o.Total == 0 {
+		return
+	}
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64)
+	}
+	if h.Total == 0 || o.MinNs < h.MinNs {
+		h.MinNs = o.MinNs
+	}
+	h.MaxNs = max(h.MaxNs, o.MaxNs)
+	for i, n := range o.Counts {
+		h.Counts[i] += n
+	}
+	h.Total += o.Total
+	h.SumNs += o.SumNs
+}
+
+// Quantile returns the latency at quantile q in [0, 1], estimated as the
+// middle of the bucket holding it.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	idx := make([]int, 0, len(h.Counts))
+	for i := range h.Counts {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	rank := uint64(q*float64(h.Total-1)) + 1
+	var seen uint64
+	for _, i := range idx {
+		seen += h.Counts[i]
+		if seen >= rank {
+			low, width := bucketRange(i)
+			mid := low + width/2
+			return time.Duration(min(max(mid, h.MinNs), h.MaxNs))
+		}
+	}
+	return time.Duration(h.MaxNs)
+}
+
+// Snapshot is a mergeable copy of one recorder's samples, used to combine
+// the results of several benchmark processes.
+type Snapshot struct {
+	Key      Key       `json:"key"`
+	Latency  Histogram `json:"latency"`
+	Errors   int       `json:"errors"`
+	Timeouts int       `json:"timeouts,omitempty"`
+	Bytes    int64     `json:"bytes"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+	// Attempts, Retried and RetryNs are as recorded by RecordAttempts.
+	Attempts int   `json:"attempts,omitempty"`
+	Retried  int   `json:"retried,omitempty"`
+	RetryNs  int64 `json:"retry_ns,omitempty"`
+}
+
+// Snapshot returns the samples recorded so far as a histogram.
+func (r *Recorder) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{Errors: r.errors, Timeouts: r.timeouts, Bytes: r.bytes, First: r.first, Last: r.last,
+		Attempts: r.attempts, Retried: r.retried, RetryNs: int64(r.retrying)}
+	s.Latency.Merge(r.hist)
+	return s
+}
+
+// Snapshots returns a snapshot per recorder in first-use order.
+func (s *Set) Snapshots() []Snapshot {
+	s.mu.Lock()
+	order := append([]Key(nil), s.order...)
+	s.mu.Unlock()
+
+	out := make([]Snapshot, 0, len(order))
+	for _, k := range order {
+		snap := s.Recorder(k).Snapshot()
+		snap.Key = k
+		out = append(out, snap)
+	}
+	return out
+}
+
+// merge adds o's samples to s.
+func (s *Snapshot) merge(o Snapshot) {
+	s.Latency.Merge(o.Latency)
+	s.Errors += o.Errors
+	s.Timeouts += o.Timeouts
+	s.Bytes += o.Bytes
+	s.Attempts += o.Attempts
+	s.Retried += o.Retried
+	s.RetryNs += o.RetryNs
+	if !o.First.IsZero() && (s.First.IsZero() || o.First.Before(s.First)) {
+		s.First = o.First
+	}
+	if o.Last.After(s.Last) {
+		s.Last = o.Last
+	}
+}
+
+// Merge combines snapshots from several processes into one summary per key,
+// in the order keys are first seen. Throughput uses the wall-clock span
+// from the earliest start to the latest completion across all of them.
+func Merge(groups ...[]Snapshot) []Summary {
+	var order []Key
+	merged := make(map[Key]*Snapshot)
+	for _, group := range groups {
+		for _, snap := range group {
+			m, ok := merged[snap.Key]
+			if !ok {
+				m = &Snapshot{Key: snap.Key}
+				merged[snap.Key] = m
+				order = append(order, snap.Key)
+			}
+			m.merge(snap)
+		}
+	}
+	out := make([]Summary, 0, len(order))
+	for _, k := range order {
+		out = append(out, merged[k].Summary())
+	}
+	return out
+}
+
+// Summary computes the same metrics as Recorder.Summary from the histogram,
+// so percentiles are accurate to the histogram's resolution.
+func (s *Snapshot) Summary() Summary {
+	h := &s.Latency
+	sum := Summary{
+		Operation: s.Key.Label(),
+		Op:        s.Key.Op,
+		Variant:   s.Key.Variant,
+		Size:      s.Key.Size,
+		Count:     int(h.Total),
+		Errors:    s.Errors,
+		Timeouts:  s.Timeouts,
+		Bytes:     s.Bytes,
+		Attempts:  s.Attempts,
+		Retried:   s.Retried,
+		RetryMs:   ms(time.Duration(s.RetryNs)),
+	}
+	if h.Total == 0 {
+		return sum
+	}
+	sum.MinMs = ms(time.Duration(h.MinNs))
+	sum.MaxMs = ms(time.Duration(h.MaxNs))
+	sum.AvgMs = ms(time.Duration(h.SumNs)) / float64(h.Total)
+	sum.P50Ms = ms(h.Quantile(0.50))
+	sum.P90Ms = ms(h.Quantile(0.90))
+	sum.P95Ms = ms(h.Quantile(0.95))
+	sum.P99Ms = ms(h.Quantile(0.99))
+
+	wall := s.Last.Sub(s.First)
+	if wall <= 0 {
+		wall = time.Duration(h.SumNs)
+	}
+	sum.WallMs = ms(wall)
+	sum.OpsPerSec = float64(sum.Count) / wall.Seconds()
+	if sum.Bytes > 0 {
+		sum.GBPerSec = (float64(sum.Bytes) / wall.Seconds()) / (1024 * 1024 * 1024)
+	}
+	return sum
+}
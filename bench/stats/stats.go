@@ -0,0 +1,278 @@
+// Package stats collects per-operation latencies and reports the same metrics
+// the standalone benchmark programs print.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Recorder accumulates latency samples for a single operation. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	hist      Histogram
+	bytes     int64
+	errors    int
+	timeouts  int
+	first     time.Time
+	last      time.Time
+	attempts  int
+	retried   int
+	retrying  time.Duration
+}
+
+// Record adds one completed operation. Failed operations are counted as errors
+// and their latency is not included in the distribution.
+func (r *Recorder) Record(start time.Time, latency time.Duration, bytes int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.first.IsZero() || start.Before(r.first) {
+		r.first = start
+	}
+	if end := start.Add(latency); end.After(r.last) {
+		r.last = end
+	}
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+	r.hist.Record(latency)
+	r.bytes += bytes
+}
+
+// RecordTimeout adds one operation that was abandoned when its timeout
+// passed. Timeouts are counted apart from errors, and their latency, which
+// is only the timeout, is not included in the distribution.
+func (r *Recorder) RecordTimeout(start time.Time, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.first.IsZero() || start.Before(r.first) {
+		r.first = start
+	}
+	if end := start.Add(latency); end.After(r.last) {
+		r.last = end
+	}
+	r.timeouts++
+}
+
+// RecordAttempts adds the attempts one operation took, and the time spent
+// on retries after its first attempt failed.
+func (r *Recorder) RecordAttempts(attempts int, retrying time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts += attempts
+	if attempts > 1 {
+		r.retried++
+		r.retrying += retrying
+	}
+}
+
+// Summary is the computed metrics for one operation.
+type Summary struct {
+	Operation string  `json:"operation"`
+	Op        string  `json:"op"`
+	Variant   string  `json:"variant,omitempty"`
+	Size      int64   `json:"size"`
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	Timeouts  int     `json:"timeouts,omitempty"`
+	Bytes     int64   `json:"bytes"`
+	MinMs     float64 `json:"min_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+	P50Ms     float64 `json:"p50_ms"`
+	P90Ms     float64 `json:"p90_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	WallMs    float64 `json:"wall_ms"`
+	OpsPerSec float64 `json:"ops_per_sec"`
+	GBPerSec  float64 `json:"gb_per_sec"`
+	// Attempts counts every attempt, first ones included; Retried counts
+	// the operations that took more than one, and RetryMs the time they
+	// spent after their first attempt, backoff included.
+	Attempts int     `json:"attempts,omitempty"`
+	Retried  int     `json:"retried,omitempty"`
+	RetryMs  float64 `json:"retry_ms,omitempty"`
+}
+
+// Summary computes metrics over the samples recorded so far. Throughput uses
+// the wall-clock span from the first start to the last completion, which
+// equals the summed latency when operations run one at a time.
+func (r *Recorder) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Summary{Count: len(r.latencies), Errors: r.errors, Timeouts: r.timeouts, Bytes: r.bytes,
+		Attempts: r.attempts, Retried: r.retried, RetryMs: ms(r.retrying)}
+	if len(r.latencies) == 0 {
+		return s
+	}
+
+	sorted := make([]float64, len(r.latencies))
+	var total time.Duration
+	for i, lat := range r.latencies {
+		sorted[i] = ms(lat)
+		total += lat
+	}
+	sort.Float64s(sorted)
+
+	s.MinMs = sorted[0]
+	s.MaxMs = sorted[len(sorted)-1]
+	s.AvgMs = ms(total) / float64(len(sorted))
+	s.P50Ms = percentile(sorted, 0.50)
+	s.P90Ms = percentile(sorted, 0.90)
+	s.P95Ms = percentile(sorted, 0.95)
+	s.P99Ms = percentile(sorted, 0.99)
+
+	wall := r.last.Sub(r.first)
+	if wall <= 0 {
+		wall = total
+	}
+	s.WallMs = ms(wall)
+	s.OpsPerSec = float64(s.Count) / wall.Seconds()
+	if s.Bytes > 0 {
+		s.GBPerSec = (float64(s.Bytes) / wall.Seconds()) / (1024 * 1024 * 1024)
+	}
+	return s
+}
+
+// percentile calculates the p-th percentile of a sorted slice of float64 values.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	k := float64(len(values)-1) * p
+	f := int(k)
+	c := f + 1
+	if c >= len(values) {
+		return values[len(values)-1]
+	}
+	d0 := values[f] * (float64(c) - k)
+	d1 := values[c] * (k - float64(f))
+	return d0 + d1
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+// Key identifies the recorder for an operation at a given object size.
+// Variant optionally splits one operation into sub-populations, such as
+// first and repeat accesses to a key.
+type Key struct {
+	Op      string `json:"op"`
+	Variant string `json:"variant,omitempty"`
+	Size    int64  `json:"size"`
+}
+
+// Label returns the heading used when printing metrics, matching the
+// "Write (Size: 1024 bytes)" form of the standalone programs.
+func (k Key) Label() string {
+	label := k.Op
+	if k.Variant != "" {
+		label += " - " + k.Variant
+	}
+	if k.Size <= 0 {
+		return label
+	}
+	return fmt.Sprintf("%s (Size: %d bytes)", label, k.Size)
+}
+
+// Set is an ordered collection of recorders, one per Key. It is safe for
+// concurrent use.
+type Set struct {
+	mu        sync.Mutex
+	order     []Key
+	recorders map[Key]*Recorder
+}
+
+// NewSet returns an empty Set.
+func NewSet() *Set {
+	return &Set{recorders: make(map[Key]*Recorder)}
+}
+
+// Recorder returns the recorder for k, creating it on first use.
+func (s *Set) Recorder(k Key) *Recorder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.recorders[k]
+	if !ok {
+		r = &Recorder{}
+		s.recorders[k] = r
+		s.order = append(s.order, k)
+	}
+	return r
+}
+
+// Summaries returns a summary per recorder in first-use order.
+func (s *Set) Summaries() []Summary {
+	s.mu.Lock()
+	order := append([]Key(nil), s.order...)
+	s.mu.Unlock()
+
+	out := make([]Summary, 0, len(order))
+	for _, k := range order {
+		sum := s.Recorder(k).Summary()
+		sum.Operation = k.Label()
+		sum.Op = k.Op
+		sum.Variant = k.Variant
+		sum.Size = k.Size
+		out = append(out, sum)
+	}
+	return out
+}
+
+// Print writes s in the format used by the standalone benchmark programs.
+func Print(w io.Writer, s Summary) {
+	if s.Count == 0 {
+		fmt.Fprintf(w, "No valid latencies for %s (errors: %d, timeouts: %d)\n", s.Operation, s.Errors, s.Timeouts)
+		return
+	}
+	fmt.Fprintf(w, "\n%s Metrics:\n", s.Operation)
+	fmt.Fprintf(w, "Min Latency: %.2f ms\n", s.MinMs)
+	fmt.Fprintf(w, "Average Latency: %.2f ms\n", s.AvgMs)
+	fmt.Fprintf(w, "P50 Latency: %.2f ms\n", s.P50Ms)
+	fmt.Fprintf(w, "P90 Latency: %.2f ms\n", s.P90Ms)
+	fmt.Fprintf(w, "P95 Latency: %.2f ms\n", s.P95Ms)
+	fmt.Fprintf(w, "P99 Latency: %.2f ms\n", s.P99Ms)
+	fmt.Fprintf(w, "Throughput: %.2f ops/sec\n", s.OpsPerSec)
+	if s.Bytes > 0 {
+		fmt.Fprintf(w, "Throughput: %.6f GB/sec\n", s.GBPerSec)
+	}
+	if s.Errors > 0 {
+		fmt.Fprintf(w, "Errors: %d\n", s.Errors)
+	}
+	if s.Timeouts > 0 {
+		fmt.Fprintf(w, "Timed Out: %d\n", s.Timeouts)
+	}
+	if s.Retried > 0 {
+		ops := s.Count + s.Errors + s.Timeouts
+		fmt.Fprintf(w, "Retried: %d of %d operations (%.2f%%), %d attempts, %.2f ms added per retried operation\n",
+			s.Retried, ops, 100*float64(s.Retried)/float64(ops), s.Attempts, s.RetryMs/float64(s.Retried))
+	}
+}
+
+// VariantFirstAttempt holds the latency of the first attempt of each
+// operation, so the time retries add can be told apart from it.
+const VariantFirstAttempt = "first attempt"
+
+// Compact drops the first-attempt variant of operations that were never
+// retried, where it only repeats the overall figures.
+func Compact(sums []Summary) []Summary {
+	retried := map[Key]bool{}
+	for _, s := range sums {
+		if s.Variant == "" && s.Retried > 0 {
+			retried[Key{Op: s.Op, Size: s.Size}] = true
+		}
+	}
+	return slices.DeleteFunc(sums, func(s Summary) bool {
+		return s.Variant == VariantFirstAttempt && !retried[Key{Op: s.Op, Size: s.Size}]
+	})
+}
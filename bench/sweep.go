@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/sweep"
+)
+
+// sweepCmd implements "bench sweep".
+func sweepCmd(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	del := fs.Bool("delete", false, "empty and delete the buckets found; without it, only list them")
+	olderThan := fs.Duration("older-than", time.Hour, "skip buckets created more recently than this, which may belong to a running benchmark")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench sweep --backend <backend> [--delete] [flags]")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	ctx := context.Background()
+	b, err := openBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	fmt.Printf("Scanning buckets on %s\n", b.Name())
+	found, unchecked, err := sweep.Scan(ctx, b)
+	if err != nil {
+		return err
+	}
+	if unchecked > 0 {
+		fmt.Printf("Could not read the owner tag of %d buckets; they are skipped\n", unchecked)
+	}
+
+	now := time.Now()
+	var orphans []sweep.Orphan
+	recent := 0
+	for _, o := range found {
+		if age := o.Age(now); age > 0 && age < *olderThan {
+			recent++
+			continue
+		}
+		orphans = append(orphans, o)
+	}
+	if recent > 0 {
+		fmt.Printf("Skipping %d buckets created in the last %s\n", recent, *olderThan)
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned benchmark buckets found")
+		return nil
+	}
+
+	fmt.Printf("\nFound %d orphaned benchmark buckets:\n", len(orphans))
+	for _, o := range orphans {
+		age := "age unknown"
+		if !o.Created.IsZero() {
+			age = "created " + formatAge(o.Age(now)) + " ago"
+		}
+		fmt.Printf("  %-60s %-18s %s\n", o.Bucket, o.Reason, age)
+	}
+	if !*del {
+		fmt.Println("\nDry run: nothing was deleted. Pass --delete to empty and delete these buckets.")
+		return nil
+	}
+
+	fmt.Println()
+	removed := 0
+	for _, o := range orphans {
+		fmt.Printf("Removing bucket: %s\n", o.Bucket)
+		if err := backend.RemoveBucket(ctx, b, o.Bucket); err != nil {
+			fmt.Printf("Failed to remove bucket %s: %v\n", o.Bucket, err)
+			continue
+		}
+		removed++
+	}
+	fmt.Printf("\nRemoved %d of %d buckets\n", removed, len(orphans))
+	if removed < len(orphans) {
+		return fmt.Errorf("%d buckets could not be removed", len(orphans)-removed)
+	}
+	return nil
+}
+
+// formatAge prints d in minutes, or in days once it spans several of them.
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= 2*day {
+		return fmt.Sprintf("%d days", d/day)
+	}
+	return d.Round(time.Minute).String()
+}
@@ -0,0 +1,98 @@
+// Package sweep finds and removes buckets left behind by benchmark runs that
+// were killed before they could clean up.
+package sweep
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+// pattern matches the names one family of benchmarks gives its buckets. The
+// first submatch is the creation time in Unix nanoseconds.
+type pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// patterns covers every benchmark in the repository and the batch demo.
+var patterns = []pattern{
+	// bench and the test-1 programs; with a zone suffix for S3 Express
+	{"test-bucket", regexp.MustCompile(`^test-bucket-(\d{16,})(--[a-z0-9-]+--x-s3)?$`)},
+	// test-2 large object test
+	{"large-object-test", regexp.MustCompile(`^large-object-test-(\d{16,})$`)},
+	// test-2 bucket list test
+	{"list-test", regexp.MustCompile(`^list-test-(\d{16,})-\d+$`)},
+	// test-2 object list test
+	{"object-list-test", regexp.MustCompile(`^object-list-test-(\d{16,})$`)},
+	// S3 Express One Zone test-1
+	{"go-express-bucket", regexp.MustCompile(`^go-express-bucket-(\d{16,})--[a-z0-9-]+--x-s3$`)},
+	// batch API demo
+	{"batch", regexp.MustCompile(`^batch-(\d{16,})-[0-9a-f]+$`)},
+}
+
+// Orphan is a bucket that looks like it was left behind by a benchmark.
+type Orphan struct {
+	Bucket string
+	// Reason names the matching pattern, or "owner tag" for the marker.
+	Reason string
+	// Created is taken from the bucket name; zero if it has no timestamp.
+	Created time.Time
+}
+
+// Age returns how long ago the bucket was created, or 0 if unknown.
+func (o Orphan) Age(now time.Time) time.Duration {
+	if o.Created.IsZero() {
+		return 0
+	}
+	return now.Sub(o.Created)
+}
+
+// Match reports whether bucket follows a benchmark naming pattern.
+func Match(bucket string) (Orphan, bool) {
+	for _, p := range patterns {
+		m := p.re.FindStringSubmatch(bucket)
+		if m == nil {
+			continue
+		}
+		o := Orphan{Bucket: bucket, Reason: p.name}
+		if ns, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			o.Created = time.Unix(0, ns)
+		}
+		return o, true
+	}
+	return Orphan{}, false
+}
+
+// Scan lists the buckets on b and returns those that match a naming pattern
+// or carry the ownership marker. Buckets whose marker could not be read are
+// counted in unchecked rather than failing the scan.
+func Scan(ctx context.Context, b backend.Backend) (orphans []Orphan, unchecked int, err error) {
+	buckets, err := b.ListBuckets(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list buckets: %w", err)
+	}
+	owner, _ := b.(backend.Owner)
+	for _, bucket := range buckets {
+		if o, ok := Match(bucket); ok {
+			orphans = append(orphans, o)
+			continue
+		}
+		if owner == nil {
+			continue
+		}
+		owned, err := owner.Owned(ctx, bucket)
+		if err != nil {
+			unchecked++
+			continue
+		}
+		if owned {
+			orphans = append(orphans, Orphan{Bucket: bucket, Reason: "owner tag"})
+		}
+	}
+	return orphans, unchecked, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+)
+
+// timeoutFlags registers the flags that bound how long each operation may
+// take.
+func timeoutFlags(fs *flag.FlagSet) *timeout.Policy {
+	p := timeout.Default()
+	fs.DurationVar(&p.Default, "timeout", p.Default, "time allowed for each operation, retries included; 0 turns timeouts off")
+	fs.DurationVar(&p.PerMiB, "timeout-per-mib", p.PerMiB, "time added to the timeout for every MiB an operation transfers")
+	fs.Func("op-timeout", "comma-separated timeouts of single operations, overriding --timeout, e.g. Read=5s,Scan=30s", func(s string) error {
+		perOp, err := timeout.ParsePerOp(s)
+		p.PerOp = perOp
+		return err
+	})
+	return &p
+}
@@ -0,0 +1,120 @@
+// Package timeout bounds how long a benchmark operation may take, so a hung
+// request is counted as timed out instead of stalling the run.
+package timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Defaults allow a minute per operation plus a second per MiB transferred,
+// which only trips on requests far slower than any healthy backend.
+const (
+	DefaultTimeout = time.Minute
+	DefaultPerMiB  = time.Second
+)
+
+// ErrTimeout is the cause of an operation's context when its timeout
+// expires, and ErrDeadline when the run's deadline is reached. Both count as
+// context.DeadlineExceeded, so backend.ErrorClass sorts them as timeouts.
+var (
+	ErrTimeout  error = deadlineError("operation timed out")
+	ErrDeadline error = deadlineError("run deadline reached")
+)
+
+// deadlineError is an error that matches context.DeadlineExceeded.
+type deadlineError string
+
+func (e deadlineError) Error() string { return string(e) }
+
+func (deadlineError) Is(target error) bool { return target == context.DeadlineExceeded }
+
+// Policy sets the time allowed for each operation, counting every attempt
+// and the backoff between them. The zero value sets no timeout.
+type Policy struct {
+	// Default applies to operations without an entry in PerOp; 0 means
+	// no timeout.
+	Default time.Duration `json:"default,omitempty"`
+	// PerOp overrides Default by operation name, e.g. "Read" or "GET".
+	PerOp map[string]time.Duration `json:"per_op,omitempty"`
+	// PerMiB is added for every MiB the operation transfers.
+	PerMiB time.Duration `json:"per_mib,omitempty"`
+}
+
+// Default returns the default policy.
+func Default() Policy {
+	return Policy{Default: DefaultTimeout, PerMiB: DefaultPerMiB}
+}
+
+// For returns the timeout of an op that transfers bytes, or 0 for none.
+func (p Policy) For(op string, bytes int64) time.Duration {
+	base, ok := p.PerOp[op]
+	if !ok {
+		base = p.Default
+	}
+	if base <= 0 {
+		return 0
+	}
+	return base + time.Duration(float64(p.PerMiB)*float64(bytes)/(1<<20))
+}
+
+// Context returns a context for one operation that expires with a cause
+// wrapping ErrTimeout once the operation's timeout has passed.
+func (p Policy) Context(ctx context.Context, op string, bytes int64) (context.Context, context.CancelFunc) {
+	d := p.For(op, bytes)
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, fmt.Errorf("%w after %s", ErrTimeout, d.Round(time.Millisecond)))
+}
+
+// Expired reports whether ctx, from Context, ended because the operation's
+// timeout passed rather than for another reason such as the run deadline.
+func Expired(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrTimeout)
+}
+
+// String describes p, e.g. "1m0s + 1s per MiB (Scan: 10s)".
+func (p Policy) String() string {
+	if p.Default <= 0 && len(p.PerOp) == 0 {
+		return "none"
+	}
+	s := "none"
+	if p.Default > 0 {
+		s = p.Default.String()
+	}
+	if p.PerMiB > 0 {
+		s += fmt.Sprintf(" + %s per MiB", p.PerMiB)
+	}
+	if len(p.PerOp) > 0 {
+		var ops []string
+		for _, op := range slices.Sorted(maps.Keys(p.PerOp)) {
+			ops = append(ops, fmt.Sprintf("%s: %s", op, p.PerOp[op]))
+		}
+		s += " (" + strings.Join(ops, ", ") + ")"
+	}
+	return s
+}
+
+// ParsePerOp parses a comma-separated list of op=duration pairs, such as
+// "Read=5s,Scan=30s".
+func ParsePerOp(s string) (map[string]time.Duration, error) {
+	perOp := map[string]time.Duration{}
+	for _, f := range strings.Split(s, ",") {
+		op, value, ok := strings.Cut(strings.TrimSpace(f), "=")
+		if !ok || op == "" {
+			return nil, fmt.Errorf("invalid entry %q: want op=duration", f)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid timeout %q for %s", value, op)
+		}
+		perOp[op] = d
+	}
+	return perOp, nil
+}
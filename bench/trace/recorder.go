@@ -0,0 +1,263 @@
+package trace
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Target is the S3-compatible endpoint requests are forwarded to.
+	Target *url.URL
+	// Credentials, when set, re-sign each request for the target host.
+	// Clients sign for the proxy's own address, which the target would
+	// otherwise reject.
+	Credentials aws.CredentialsProvider
+	// Region is the signing region, e.g. "us-east-1" or "auto" for Tigris.
+	Region string
+	// Logger receives forwarding errors; nil means the standard logger.
+	Logger *log.Logger
+}
+
+// Recorder is a reverse proxy in front of an S3-compatible endpoint that
+// appends every object operation it forwards to a JSONL trace. Clients must
+// address it path-style, e.g. BaseEndpoint "http://localhost:8080" with
+// UsePathStyle set.
+type Recorder struct {
+	cfg   RecorderConfig
+	proxy *httputil.ReverseProxy
+
+	mu      sync.Mutex
+	out     *Writer
+	uploads map[string]int64 // bytes uploaded so far per multipart upload ID
+	count   int
+}
+
+// NewRecorder returns a Recorder that writes its trace to out.
+func NewRecorder(cfg RecorderConfig, out io.Writer) *Recorder {
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	rec := &Recorder{
+		cfg:     cfg,
+		out:     NewWriter(out),
+		uploads: make(map[string]int64),
+	}
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true // S3 signs the path as sent
+	})
+	rec.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(cfg.Target)
+			if cfg.Credentials != nil {
+				if err := rec.sign(pr.Out.Context(), signer, pr.Out); err != nil {
+					cfg.Logger.Printf("Failed to sign request %s %s: %v", pr.Out.Method, pr.Out.URL.Path, err)
+				}
+			}
+		},
+		ErrorLog: cfg.Logger,
+	}
+	return rec
+}
+
+// Count returns the number of records written so far.
+func (rec *Recorder) Count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.count
+}
+
+// sign replaces the client's signature with one for the target host.
+func (rec *Recorder) sign(ctx context.Context, signer *v4.Signer, r *http.Request) error {
+	creds, err := rec.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+		r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	r.Header.Del("Authorization")
+	r.Header.Del("X-Amz-Date")
+	r.Header.Del("X-Amz-Security-Token")
+	return signer.SignHTTP(ctx, creds, r, payloadHash, "s3", rec.cfg.Region, time.Now().UTC())
+}
+
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	req := classify(r)
+	cw := &countingWriter{ResponseWriter: w, status: http.StatusOK}
+
+	rec.proxy.ServeHTTP(cw, r)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	switch req.kind {
+	case kindPart:
+		if cw.status < 300 {
+			rec.uploads[req.uploadID] += req.size
+		}
+		return
+	case kindComplete:
+		if cw.status >= 300 {
+			// The upload stays open; the client may retry or abort it.
+			return
+		}
+		req.rec.Size = rec.uploads[req.uploadID]
+		delete(rec.uploads, req.uploadID)
+	case kindAbort:
+		delete(rec.uploads, req.uploadID)
+		return
+	case kindOther:
+		return
+	}
+	traced := req.rec
+	traced.Time = start.UTC()
+	traced.Status = cw.status
+	traced.LatencyMs = float64(time.Since(start).Nanoseconds()) / 1e6
+	if traced.Op == OpGet && cw.status < 300 {
+		traced.Size = cw.bytes
+	}
+	if err := rec.out.Write(traced); err != nil {
+		rec.cfg.Logger.Printf("Failed to write trace record: %v", err)
+		return
+	}
+	rec.count++
+}
+
+// requestKind says how a forwarded request is traced.
+type requestKind int
+
+const (
+	kindOther    requestKind = iota // not traced, e.g. bucket or ACL calls
+	kindObject                      // traced as rec
+	kindPart                        // multipart part; bytes added to its upload
+	kindComplete                    // multipart completion; traced as one PUT
+	kindAbort                       // multipart abort; its upload is dropped
+)
+
+type classified struct {
+	kind     requestKind
+	rec      Record
+	uploadID string
+	size     int64
+}
+
+// classify maps a path-style S3 request to the trace operation it performs.
+func classify(r *http.Request) classified {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	q := r.URL.Query()
+	c := classified{rec: Record{Bucket: bucket, Key: key}, uploadID: q.Get("uploadId"), size: requestSize(r)}
+	if bucket == "" {
+		return c
+	}
+
+	if key == "" {
+		if r.Method == http.MethodGet && (len(q) == 0 || q.Has("list-type") || q.Has("prefix")) {
+			c.kind = kindObject
+			c.rec.Op = OpList
+			c.rec.Key = q.Get("prefix")
+		}
+		if r.Method == http.MethodHead && len(q) == 0 {
+			c.kind, c.rec.Op = kindObject, OpHeadBucket
+		}
+		return c
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if len(q) == 0 || onlyParams(q, "versionId", "partNumber", "x-id") {
+			c.kind, c.rec.Op = kindObject, OpGet
+		}
+	case http.MethodHead:
+		c.kind, c.rec.Op = kindObject, OpHead
+	case http.MethodDelete:
+		switch {
+		case c.uploadID != "":
+			c.kind = kindAbort
+		case onlyParams(q, "versionId", "x-id"):
+			c.kind, c.rec.Op = kindObject, OpDelete
+		}
+	case http.MethodPut:
+		switch {
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			// Server-side copies are not replayed.
+		case c.uploadID != "" && q.Has("partNumber"):
+			c.kind = kindPart
+		case onlyParams(q, "x-id"):
+			c.kind, c.rec.Op = kindObject, OpPut
+			c.rec.Size = c.size
+		}
+	case http.MethodPost:
+		if c.uploadID != "" {
+			c.kind, c.rec.Op = kindComplete, OpPut
+		}
+	}
+	return c
+}
+
+// onlyParams reports whether q contains no parameters other than allowed.
+func onlyParams(q url.Values, allowed ...string) bool {
+	for name := range q {
+		ok := false
+		for _, a := range allowed {
+			if name == a {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// requestSize returns the object bytes in a request body, looking through
+// aws-chunked encoding.
+func requestSize(r *http.Request) int64 {
+	if decoded := r.Header.Get("X-Amz-Decoded-Content-Length"); decoded != "" {
+		if n, err := strconv.ParseInt(decoded, 10, 64); err == nil {
+			return n
+		}
+	}
+	if r.ContentLength > 0 {
+		return r.ContentLength
+	}
+	return 0
+}
+
+// countingWriter records the status and body size of a response.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (cw *countingWriter) WriteHeader(status int) {
+	cw.status = status
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	cw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
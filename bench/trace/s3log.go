@@ -0,0 +1,137 @@
+package trace
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3LogTimeLayout is the timestamp format of S3 server access logs, e.g.
+// [06/Feb/2019:00:00:38 +0000].
+const s3LogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Field positions in an S3 server access log entry.
+const (
+	s3FieldBucket     = 1
+	s3FieldTime       = 2
+	s3FieldOperation  = 6
+	s3FieldKey        = 7
+	s3FieldRequestURI = 8
+	s3FieldStatus     = 9
+	s3FieldBytesSent  = 11
+	s3FieldObjectSize = 12
+	s3FieldTotalTime  = 13
+	s3MinFields       = 14
+)
+
+// s3LogOps maps access log operation names to replayable operations.
+// Completing a multipart upload (REST.POST.UPLOAD) is replayed as one PUT of
+// the whole object; the individual parts are not replayed.
+var s3LogOps = map[string]Op{
+	"REST.GET.OBJECT":    OpGet,
+	"REST.PUT.OBJECT":    OpPut,
+	"REST.POST.UPLOAD":   OpPut,
+	"REST.DELETE.OBJECT": OpDelete,
+	"REST.HEAD.OBJECT":   OpHead,
+	"REST.GET.BUCKET":    OpList,
+	"REST.HEAD.BUCKET":   OpHeadBucket,
+}
+
+// parseS3Log parses one S3 server access log entry. ok is false for
+// operations that are not replayed.
+func parseS3Log(line string) (rec Record, ok bool, err error) {
+	fields, err := splitS3Log(line)
+	if err != nil {
+		return Record{}, false, err
+	}
+	if len(fields) < s3MinFields {
+		return Record{}, false, fmt.Errorf("access log entry has %d fields, want at least %d", len(fields), s3MinFields)
+	}
+
+	op, ok := s3LogOps[fields[s3FieldOperation]]
+	if !ok {
+		return Record{}, false, nil
+	}
+	t, err := time.Parse(s3LogTimeLayout, fields[s3FieldTime])
+	if err != nil {
+		return Record{}, false, fmt.Errorf("invalid time %q: %w", fields[s3FieldTime], err)
+	}
+
+	rec = Record{
+		Time:      t,
+		Op:        op,
+		Bucket:    fields[s3FieldBucket],
+		Status:    int(dashInt(fields[s3FieldStatus])),
+		Size:      dashInt(fields[s3FieldObjectSize]),
+		LatencyMs: float64(dashInt(fields[s3FieldTotalTime])),
+	}
+	if rec.Size == 0 && op == OpGet {
+		rec.Size = dashInt(fields[s3FieldBytesSent])
+	}
+
+	if op == OpList {
+		rec.Key = listPrefix(fields[s3FieldRequestURI])
+	} else if key := fields[s3FieldKey]; key != "-" {
+		rec.Key = key
+		if unescaped, err := url.PathUnescape(key); err == nil {
+			rec.Key = unescaped
+		}
+	}
+	return rec, true, nil
+}
+
+// splitS3Log splits an access log entry on spaces, keeping "quoted" and
+// [bracketed] fields together and stripping their delimiters.
+func splitS3Log(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+		case '"', '[':
+			closer := byte('"')
+			if line[i] == '[' {
+				closer = ']'
+			}
+			end := strings.IndexByte(line[i+1:], closer)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %c field", line[i])
+			}
+			fields = append(fields, line[i+1:i+1+end])
+			i += end + 2
+		default:
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+	return fields, nil
+}
+
+// listPrefix extracts the prefix query parameter from a request line such
+// as "GET /bucket?list-type=2&prefix=logs%2F HTTP/1.1".
+func listPrefix(requestLine string) string {
+	parts := strings.Fields(requestLine)
+	if len(parts) < 2 {
+		return ""
+	}
+	u, err := url.ParseRequestURI(parts[1])
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("prefix")
+}
+
+// dashInt parses an access log number, where "-" means zero.
+func dashInt(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// s3LogLine returns an access log entry in the documented field order.
+func s3LogLine(op, key, requestURI, status, bytesSent, objectSize, totalTime string) string {
+	return fmt.Sprintf(`79a59df900b949e5 logs [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e5 3E57427F3EXAMPLE %s %s "%s" %s - %s %s %s 7 "-" "S3Console/0.4" -`,
+		op, key, requestURI, status, bytesSent, objectSize, totalTime)
+}
+
+func TestParseS3Log(t *testing.T) {
+	at := time.Date(2019, time.February, 6, 0, 0, 38, 0, time.UTC)
+	tests := []struct {
+		name string
+		line string
+		want Record
+		ok   bool
+	}{
+		{
+			name: "get",
+			line: s3LogLine("REST.GET.OBJECT", "a/b.txt", "GET /logs/a/b.txt HTTP/1.1", "200", "1024", "1024", "12"),
+			want: Record{Time: at, Op: OpGet, Bucket: "logs", Key: "a/b.txt", Size: 1024, Status: 200, LatencyMs: 12},
+			ok:   true,
+		},
+		{
+			name: "get size from bytes sent",
+			line: s3LogLine("REST.GET.OBJECT", "a", "GET /logs/a HTTP/1.1", "200", "512", "-", "3"),
+			want: Record{Time: at, Op: OpGet, Bucket: "logs", Key: "a", Size: 512, Status: 200, LatencyMs: 3},
+			ok:   true,
+		},
+		{
+			name: "escaped key",
+			line: s3LogLine("REST.PUT.OBJECT", "dir/my%20file", "PUT /logs/dir/my%20file HTTP/1.1", "200", "-", "10", "5"),
+			want: Record{Time: at, Op: OpPut, Bucket: "logs", Key: "dir/my file", Size: 10, Status: 200, LatencyMs: 5},
+			ok:   true,
+		},
+		{
+			name: "multipart completion",
+			line: s3LogLine("REST.POST.UPLOAD", "big", "POST /logs/big?uploadId=x HTTP/1.1", "200", "-", "10485760", "80"),
+			want: Record{Time: at, Op: OpPut, Bucket: "logs", Key: "big", Size: 10485760, Status: 200, LatencyMs: 80},
+			ok:   true,
+		},
+		{
+			name: "list prefix",
+			line: s3LogLine("REST.GET.BUCKET", "-", "GET /logs?list-type=2&prefix=2019%2F HTTP/1.1", "200", "900", "-", "20"),
+			want: Record{Time: at, Op: OpList, Bucket: "logs", Key: "2019/", Size: 0, Status: 200, LatencyMs: 20},
+			ok:   true,
+		},
+		{
+			name: "head bucket",
+			line: s3LogLine("REST.HEAD.BUCKET", "-", "HEAD /logs HTTP/1.1", "404", "-", "-", "1"),
+			want: Record{Time: at, Op: OpHeadBucket, Bucket: "logs", Status: 404, LatencyMs: 1},
+			ok:   true,
+		},
+		{
+			name: "not replayed",
+			line: s3LogLine("REST.GET.ACL", "a", "GET /logs/a?acl HTTP/1.1", "200", "300", "-", "2"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := parseS3Log(tt.line)
+			if err != nil {
+				t.Fatalf("parseS3Log: %v", err)
+			}
+			if got.Time.Equal(tt.want.Time) {
+				got.Time = tt.want.Time
+			}
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseS3Log = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseS3LogMalformed(t *testing.T) {
+	get := s3LogLine("REST.GET.OBJECT", "a", "GET /logs/a HTTP/1.1", "200", "1", "1", "1")
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"too few fields", "owner logs [06/Feb/2019:00:00:38 +0000] 192.0.2.3", "fields"},
+		{"unterminated quote", get + ` "Mozilla/5.0`, "unterminated"},
+		{"unterminated bracket", strings.Replace(get, "+0000]", "+0000", 1), "unterminated"},
+		{"bad time", strings.Replace(get, "06/Feb/2019", "2019-02-06", 1), "invalid time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseS3Log(tt.line)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseS3Log error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package trace reads and writes object storage access traces: S3 server
+// access logs and the runner's own JSONL format.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Op is the storage operation a trace record describes.
+type Op string
+
+const (
+	OpGet    Op = "GET"
+	OpPut    Op = "PUT"
+	OpDelete Op = "DELETE"
+	OpHead   Op = "HEAD"
+	// OpList lists a bucket; the record's Key holds the prefix.
+	OpList Op = "LIST"
+	// OpHeadBucket checks that a bucket exists; the record has no Key.
+	OpHeadBucket Op = "HEAD_BUCKET"
+)
+
+// Record is one operation in a trace. It is also the JSONL line format:
+//
+//	{"time":"2025-05-01T12:00:00.123Z","op":"PUT","bucket":"logs","key":"a/b","size":1024}
+type Record struct {
+	Time      time.Time `json:"time"`
+	Op        Op        `json:"op"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMs float64   `json:"latency_ms,omitempty"`
+}
+
+// Succeeded reports whether the logged request succeeded: its status is 2xx,
+// or no status was logged.
+func (r Record) Succeeded() bool {
+	return r.Status == 0 || (r.Status >= 200 && r.Status < 300)
+}
+
+// Successful returns the records that succeeded when they were logged, and
+// how many did not.
+func Successful(records []Record) ([]Record, int) {
+	kept := make([]Record, 0, len(records))
+	for _, rec := range records {
+		if rec.Succeeded() {
+			kept = append(kept, rec)
+		}
+	}
+	return kept, len(records) - len(kept)
+}
+
+// Seeds returns a PUT of the logged size for every object the records read
+// before they write it, so a replay can create those objects up front.
+// Records that failed when they were logged neither read nor write.
+func Seeds(records []Record) []Record {
+	type object struct{ bucket, key string }
+	written := make(map[object]bool)
+	var seeds []Record
+	for _, rec := range records {
+		if !rec.Succeeded() {
+			continue
+		}
+		obj := object{rec.Bucket, rec.Key}
+		switch rec.Op {
+		case OpPut, OpDelete:
+			written[obj] = true
+		case OpGet, OpHead:
+			if written[obj] {
+				continue
+			}
+			written[obj] = true
+			seeds = append(seeds, Record{Op: OpPut, Bucket: rec.Bucket, Key: rec.Key, Size: rec.Size})
+		}
+	}
+	return seeds
+}
+
+// Trace formats accepted by Read.
+const (
+	FormatAuto  = "auto"
+	FormatJSONL = "jsonl"
+	FormatS3Log = "s3log"
+)
+
+// ReadFile reads a trace from path; see Read.
+func ReadFile(path, format string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, format)
+}
+
+// Read parses every record in r and returns them sorted by time. With
+// FormatAuto the format is chosen per line: lines starting with "{" are JSONL,
+// anything else is an S3 access log entry. Access log entries for operations
+// the runner cannot replay, such as ACL or website requests, are dropped.
+func Read(r io.Reader, format string) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		lineFormat := format
+		if lineFormat == FormatAuto || lineFormat == "" {
+			lineFormat = FormatS3Log
+			if strings.HasPrefix(text, "{") {
+				lineFormat = FormatJSONL
+			}
+		}
+
+		var rec Record
+		var ok bool
+		var err error
+		switch lineFormat {
+		case FormatJSONL:
+			err = json.Unmarshal([]byte(text), &rec)
+			ok = err == nil
+		case FormatS3Log:
+			rec, ok, err = parseS3Log(text)
+		default:
+			return nil, fmt.Errorf("unknown trace format %q", format)
+		}
+		if err == nil && ok {
+			err = rec.validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+// validate rejects a record the runner could not replay.
+func (r Record) validate() error {
+	switch r.Op {
+	case OpGet, OpPut, OpDelete, OpHead, OpList, OpHeadBucket:
+	default:
+		return fmt.Errorf("unknown operation %q", r.Op)
+	}
+	if r.Size < 0 {
+		return fmt.Errorf("negative size %d", r.Size)
+	}
+	return nil
+}
+
+// Writer appends records to a JSONL trace. It is not safe for concurrent use.
+type Writer struct {
+	enc *json.Encoder
+}
+
+// NewWriter returns a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write appends one record.
+func (w *Writer) Write(rec Record) error {
+	return w.enc.Encode(rec)
+}
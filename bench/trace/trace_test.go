@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	input := strings.Join([]string{
+		`{"time":"2025-05-01T12:00:02Z","op":"GET","key":"b","size":20}`,
+		``,
+		s3LogLine("REST.PUT.OBJECT", "a", "PUT /logs/a HTTP/1.1", "200", "-", "10", "5"),
+		s3LogLine("REST.GET.ACL", "a", "GET /logs/a?acl HTTP/1.1", "200", "300", "-", "2"),
+		`{"time":"2025-05-01T12:00:01Z","op":"DELETE","key":"b"}`,
+	}, "\n")
+	records, err := Read(strings.NewReader(input), FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rec := range records {
+		got = append(got, string(rec.Op)+" "+rec.Key)
+	}
+	// Sorted by time, with the ACL request dropped.
+	want := []string{"PUT a", "DELETE b", "GET b"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Read = %v, want %v", got, want)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{"negative size", FormatJSONL, `{"time":"2025-05-01T12:00:00Z","op":"PUT","key":"a","size":-1}`, "line 1: negative size -1"},
+		{"negative log size", FormatAuto, s3LogLine("REST.PUT.OBJECT", "a", "PUT /logs/a HTTP/1.1", "200", "-", "-5", "1"), "line 1: negative size -5"},
+		{"unknown op", FormatJSONL, "{\"time\":\"2025-05-01T12:00:00Z\",\"op\":\"GET\"}\n{\"op\":\"COPY\",\"key\":\"a\"}", `line 2: unknown operation "COPY"`},
+		{"missing op", FormatJSONL, `{"time":"2025-05-01T12:00:00Z","key":"a"}`, `line 1: unknown operation ""`},
+		{"bad json", FormatJSONL, `{"op":"GET",`, "line 1:"},
+		{"log line as jsonl", FormatJSONL, s3LogLine("REST.GET.OBJECT", "a", "GET /logs/a HTTP/1.1", "200", "1", "1", "1"), "line 1:"},
+		{"bad log line", FormatAuto, "\n\nowner logs [06/Feb/2019", "line 3: unterminated"},
+		{"unknown format", "csv", "a,b", `unknown trace format "csv"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []Record
+		want    []Record
+	}{
+		{
+			name: "read before write",
+			records: []Record{
+				{Op: OpGet, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpHead, Bucket: "b", Key: "c", Size: 5},
+			},
+			want: []Record{
+				{Op: OpPut, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpPut, Bucket: "b", Key: "c", Size: 5},
+			},
+		},
+		{
+			name: "written first",
+			records: []Record{
+				{Op: OpPut, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpGet, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpDelete, Bucket: "b", Key: "c"},
+				{Op: OpGet, Bucket: "b", Key: "c", Size: 3},
+			},
+		},
+		{
+			name: "seeded once",
+			records: []Record{
+				{Op: OpHead, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpGet, Bucket: "b", Key: "a", Size: 10},
+			},
+			want: []Record{{Op: OpPut, Bucket: "b", Key: "a", Size: 10}},
+		},
+		{
+			name: "same key in another bucket",
+			records: []Record{
+				{Op: OpPut, Bucket: "b", Key: "a", Size: 10},
+				{Op: OpGet, Bucket: "other", Key: "a", Size: 7},
+			},
+			want: []Record{{Op: OpPut, Bucket: "other", Key: "a", Size: 7}},
+		},
+		{
+			name: "failed records",
+			records: []Record{
+				{Op: OpGet, Bucket: "b", Key: "missing", Status: 404},
+				{Op: OpHead, Bucket: "b", Key: "denied", Status: 403},
+				{Op: OpPut, Bucket: "b", Key: "a", Size: 10, Status: 503},
+				{Op: OpGet, Bucket: "b", Key: "a", Size: 10, Status: 200},
+			},
+			want: []Record{{Op: OpPut, Bucket: "b", Key: "a", Size: 10}},
+		},
+		{
+			name: "lists and bucket checks",
+			records: []Record{
+				{Op: OpList, Bucket: "b", Key: "prefix/"},
+				{Op: OpHeadBucket, Bucket: "b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Seeds(tt.records); !slices.Equal(got, tt.want) {
+				t.Errorf("Seeds = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuccessful(t *testing.T) {
+	records := []Record{
+		{Op: OpGet, Key: "a"},
+		{Op: OpGet, Key: "b", Status: 200},
+		{Op: OpPut, Key: "c", Status: 204},
+		{Op: OpGet, Key: "d", Status: 304},
+		{Op: OpGet, Key: "e", Status: 404},
+		{Op: OpPut, Key: "f", Status: 503},
+	}
+	kept, failed := Successful(records)
+	if !slices.Equal(kept, records[:3]) || failed != 3 {
+		t.Errorf("Successful = %+v, %d; want %+v, 3", kept, failed, records[:3])
+	}
+}
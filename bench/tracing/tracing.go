@@ -0,0 +1,148 @@
+// Package tracing wraps every benchmark operation in an OpenTelemetry span,
+// so a latency outlier can be traced back to the request, key and retry that
+// caused it. Spans are exported over OTLP or to a local JSON file.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Accelerated-Cloud-Storage/Benchmarks/bench"
+
+// Span attribute keys set on operation spans.
+const (
+	AttrBackend    = attribute.Key("bench.backend")
+	AttrPhase      = attribute.Key("bench.phase")
+	AttrBucket     = attribute.Key("bench.bucket")
+	AttrKey        = attribute.Key("bench.key")
+	AttrSize       = attribute.Key("bench.size")
+	AttrVariant    = attribute.Key("bench.variant")
+	AttrAttempt    = attribute.Key("bench.attempt")
+	AttrAttempts   = attribute.Key("bench.attempts")
+	AttrErrorClass = attribute.Key("bench.error_class")
+)
+
+// Config selects where spans are exported. Both destinations may be set.
+type Config struct {
+	// Endpoint is an OTLP/HTTP collector, e.g. "localhost:4318" or
+	// "https://collector:4318".
+	Endpoint string
+	// File receives every span as a JSON object.
+	File string
+}
+
+// Enabled reports whether cfg exports spans anywhere.
+func (cfg Config) Enabled() bool {
+	return cfg.Endpoint != "" || cfg.File != ""
+}
+
+// Setup installs a global tracer provider exporting to the destinations in
+// cfg. The returned function flushes outstanding spans and must be called
+// before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	var opts []sdktrace.TracerProviderOption
+	var closers []func() error
+	if cfg.Endpoint != "" {
+		exp, err := otlptracehttp.New(ctx, otlpOptions(cfg.Endpoint)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	if cfg.File != "" {
+		f, err := os.Create(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create span file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to create span file exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+		closers = append(closers, f.Close)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName("bench")))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(append(opts, sdktrace.WithResource(res))...)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		errs := []error{tp.Shutdown(ctx)}
+		for _, c := range closers {
+			errs = append(errs, c())
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// otlpOptions accepts either host:port or a full URL for the collector.
+func otlpOptions(endpoint string) []otlptracehttp.Option {
+	if strings.Contains(endpoint, "://") {
+		return []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	}
+	return []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure()}
+}
+
+// Tracer returns the tracer benchmark spans are created with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// attemptsKey holds the attempt counter of the logical operation in flight.
+type attemptsKey struct{}
+
+// Start begins the span for one logical benchmark operation. Client
+// instrumentation underneath, such as the S3 retry middleware, nests its
+// spans under it and counts attempts through the returned context.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, attemptsKey{}, new(atomic.Int64)), span
+}
+
+// End records the outcome of an operation span started with Start and ends
+// it. attempts is the number of times the benchmark tried the operation;
+// client instrumentation that counts its own attempts raises it when the
+// client retried internally as well. class is the error class reported for
+// a failed operation.
+func End(ctx context.Context, span trace.Span, attempts int, err error, class string) {
+	n := max(int64(attempts), 1)
+	if c, ok := ctx.Value(attemptsKey{}).(*atomic.Int64); ok {
+		n = max(n, c.Load())
+	}
+	span.SetAttributes(AttrAttempts.Int64(n))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(AttrErrorClass.String(class))
+	}
+	span.End()
+}
+
+// StartAttempt begins a child span for one attempt of the operation in ctx,
+// numbering attempts from 1.
+func StartAttempt(ctx context.Context, name string) (context.Context, trace.Span) {
+	attempt := int64(1)
+	if n, ok := ctx.Value(attemptsKey{}).(*atomic.Int64); ok {
+		attempt = n.Add(1)
+	}
+	return Tracer().Start(ctx, fmt.Sprintf("%s attempt %d", name, attempt),
+		trace.WithAttributes(AttrAttempt.Int64(attempt)))
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/retry"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/timeout"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/tune"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// tuneCmd implements "bench tune".
+func tuneCmd(args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ContinueOnError)
+	cfg := backendFlags(fs)
+	wf := workloadFlags(fs)
+	retries := retryFlags(fs)
+	timeouts := timeoutFlags(fs)
+	m := tune.Matrix{}
+	fs.Func("sizes", "comma-separated object sizes in bytes (default: --size)", intList(&m.Sizes))
+	fs.Func("idle-conns-per-host", "comma-separated idle connection limits per host; 0 is the SDK default (default \"10,100\")", intList(&m.MaxIdleConnsPerHost))
+	fs.Func("keep-alive", "keep-alive settings to try: on, off or on,off (default \"on\")", switchList(&m.DisableKeepAlives))
+	fs.Func("http2", "HTTP/2 settings to try: on, off or on,off (default \"on,off\")", switchList(&m.DisableHTTP2))
+	fs.Func("read-buffers", "comma-separated read buffer sizes in bytes; 0 is the default 4096", intList(&m.ReadBufferSize))
+	fs.Func("write-buffers", "comma-separated write buffer sizes in bytes; 0 is the default 4096", intList(&m.WriteBufferSize))
+	fs.Func("compression", "response compression settings to try: on, off or on,off (default \"on\")", switchList(&m.DisableCompression))
+	out := fs.String("out", "", "write every cell and the best transport per size as JSON to this file")
+	statePath := fs.String("state", "", "checkpoint completed cells and loaded buckets to this file, so the run can be resumed")
+	resume := fs.Bool("resume", false, "continue the run checkpointed in --state, skipping the cells it completed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bench tune <preset> --backend <backend> [flags]")
+		fmt.Fprintln(fs.Output(), "\nLoads the preset once per size, then runs it for every combination of the transport lists.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := retries.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+	if *resume && *statePath == "" {
+		return errors.New("--resume needs --state")
+	}
+	// Each cell opens the backend with its own transport, so only check the
+	// name here.
+	if !slices.Contains(backend.Names(), cfg.Name) {
+		return fmt.Errorf("--backend must be one of %s", strings.Join(backend.Names(), ", "))
+	}
+	if !backend.AppliesTransport(cfg.Name) {
+		return fmt.Errorf("backend %s manages its own connections and ignores the transport settings, so there is nothing to tune", cfg.Name)
+	}
+	w, err := wf.workload(positional[0])
+	if err != nil {
+		return err
+	}
+	if m.Sizes == nil {
+		m.Sizes = []int{w.ObjectSize}
+	}
+	if m.MaxIdleConnsPerHost == nil {
+		m.MaxIdleConnsPerHost = []int{10, 100}
+	}
+	if m.DisableHTTP2 == nil {
+		m.DisableHTTP2 = []bool{false, true}
+	}
+	cells := m.Cells()
+
+	st := &tune.State{Backend: cfg.Name, Scenario: runner.PresetScenario(w), Workload: w, Seed: *wf.seed, Cells: cells}
+	if *resume {
+		saved, err := tune.LoadState(*statePath)
+		if err != nil {
+			return err
+		}
+		if err := saved.Matches(st); err != nil {
+			return fmt.Errorf("cannot resume from %s: %w", *statePath, err)
+		}
+		st = saved
+	} else if *statePath != "" {
+		if _, err := os.Stat(*statePath); err == nil {
+			return fmt.Errorf("state file %s already exists; pass --resume to continue that run, or remove it", *statePath)
+		}
+	}
+
+	// Cells run back to back, so only the summary lines are printed.
+	progress.Enabled = false
+
+	ctx := context.Background()
+	tracker, ctx := cleanup.New(ctx, os.Stdout)
+	defer tracker.Close()
+
+	fmt.Printf("Tuning workload %s (%s) on %s across %d cells\n", w.Name, w.Title, cfg.Name, len(cells))
+	fmt.Println("======================================")
+	if *resume {
+		fmt.Printf("Resuming from %s: %d of %d cells already done\n", *statePath, len(st.Done), len(cells))
+	}
+
+	t := &tuner{cfg: *cfg, workload: w, seed: *wf.seed, retries: *retries, timeouts: *timeouts, tracker: tracker, state: st, path: *statePath, tracked: map[int]*cleanup.Resource{}, clients: map[int]backend.Backend{}}
+	if err := t.save(); err != nil {
+		return err
+	}
+	rep := tune.Report{Backend: cfg.Name, Scenario: st.Scenario}
+	for i, c := range cells {
+		o, done := st.Outcome(c)
+		if !done {
+			o = t.run(ctx, c)
+		}
+		if cleanup.Interrupted(ctx) && o.Err != "" {
+			// The cell never got going before the signal.
+			break
+		}
+		tune.PrintOutcome(os.Stdout, i+1, len(cells), o)
+		rep.Outcomes = append(rep.Outcomes, o)
+		if cleanup.Interrupted(ctx) {
+			break
+		}
+	}
+	rep.Interrupted = cleanup.Interrupted(ctx)
+	rep.Best = tune.Best(rep.Outcomes)
+	rep.Print(os.Stdout)
+
+	if *statePath != "" {
+		for _, bk := range st.Buckets {
+			fmt.Printf("\nKeeping bucket %s for --resume; remove it with \"bench sweep\" if the run is not resumed\n", bk.Name)
+		}
+	}
+	if *out != "" {
+		if err := rep.WriteFile(*out); err != nil {
+			return err
+		}
+		fmt.Printf("\nReport written to %s\n", *out)
+	}
+	return nil
+}
+
+// tuner runs the cells of a matrix. The cells of each size share one bucket,
+// loaded once and removed after the last of them completes. With a state
+// file, progress is checkpointed after every step and the buckets are left
+// in place when the program stops, so a resumed run can pick them up.
+type tuner struct {
+	cfg      backend.Config
+	workload workload.Workload
+	seed     int64
+	retries  retry.Policy
+	timeouts timeout.Policy
+	tracker  *cleanup.Tracker
+	state    *tune.State
+	path     string
+	// tracked holds the buckets removed on exit, by size, when there is
+	// no state file to resume from.
+	tracked map[int]*cleanup.Resource
+	// clients holds the client of each size's bucket, by size.
+	clients map[int]backend.Backend
+}
+
+// save checkpoints the state, if there is a state file.
+func (t *tuner) save() error {
+	if t.path == "" {
+		return nil
+	}
+	return t.state.Save(t.path)
+}
+
+// run runs the workload's run phase at the cell's size against the bucket
+// of that size, through a client opened with the cell's transport.
+func (t *tuner) run(ctx context.Context, c tune.Cell) tune.Outcome {
+	w := t.workload
+	w.ObjectSize = c.Size
+	if err := w.Validate(); err != nil {
+		return tune.Outcome{Cell: c, Err: err.Error()}
+	}
+	bucket, err := t.bucket(ctx, w)
+	if err != nil {
+		return tune.Outcome{Cell: c, Err: err.Error()}
+	}
+	cfg := t.cfg
+	cfg.Transport = c.Transport
+	b, err := openBackend(ctx, &cfg)
+	if err != nil {
+		return tune.Outcome{Cell: c, Err: err.Error()}
+	}
+	defer b.Close()
+	r := &runner.Runner{Backend: b, Workload: w, Seed: t.seed, Options: runner.Options{Retry: t.retries, Timeout: t.timeouts, Out: io.Discard}, Bucket: bucket, Preloaded: true}
+	res, err := r.Run(ctx)
+	if err != nil {
+		return tune.Outcome{Cell: c, Err: err.Error()}
+	}
+	o := tune.Measure(c, res)
+	if o.Incomplete {
+		return o
+	}
+	t.state.Done = append(t.state.Done, o)
+	if !t.state.Remaining(c.Size) {
+		t.release(ctx, c.Size)
+	}
+	if err := t.save(); err != nil {
+		fmt.Printf("Failed to checkpoint: %v\n", err)
+	}
+	return o
+}
+
+// client returns the client that creates, loads and removes the bucket of
+// size, opened with the command's own transport. It stays open until the
+// bucket is removed, so a removal on exit still has a client to go through.
+func (t *tuner) client(ctx context.Context, size int) (backend.Backend, error) {
+	if b := t.clients[size]; b != nil {
+		return b, nil
+	}
+	cfg := t.cfg
+	b, err := openBackend(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.clients[size] = b
+	return b, nil
+}
+
+// bucket returns the bucket for w's size, creating and loading it first if
+// no earlier cell or run did.
+func (t *tuner) bucket(ctx context.Context, w workload.Workload) (string, error) {
+	b, err := t.client(ctx, w.ObjectSize)
+	if err != nil {
+		return "", err
+	}
+	if t.state.Bucket(w.ObjectSize) == nil {
+		name := backend.BucketName(b, fmt.Sprintf("test-bucket-%d", time.Now().UnixNano()))
+		if err := b.CreateBucket(ctx, name); err != nil {
+			return "", fmt.Errorf("failed to create bucket: %w", err)
+		}
+		if err := backend.MarkOwned(ctx, b, name); err != nil {
+			fmt.Printf("Failed to mark bucket as benchmark-owned: %v\n", err)
+		}
+		if t.path == "" {
+			t.tracked[w.ObjectSize] = t.tracker.Track(cleanup.Bucket, name, func(ctx context.Context) error {
+				return backend.RemoveBucket(ctx, b, name)
+			})
+		}
+		t.state.Buckets = append(t.state.Buckets, tune.Bucket{Size: w.ObjectSize, Name: name})
+		if err := t.save(); err != nil {
+			return "", err
+		}
+	}
+	bk := t.state.Bucket(w.ObjectSize)
+	if bk.Loaded {
+		return bk.Name, nil
+	}
+
+	w.OperationCount = 0
+	r := &runner.Runner{Backend: b, Workload: w, Seed: t.seed, Options: runner.Options{Retry: t.retries, Timeout: t.timeouts, Out: io.Discard}, Bucket: bk.Name}
+	res, err := r.Run(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Phases) == 0 || res.Phases[0].Incomplete {
+		return "", fmt.Errorf("load phase into %s did not finish", bk.Name)
+	}
+	bk.Loaded = true
+	if err := t.save(); err != nil {
+		return "", err
+	}
+	return bk.Name, nil
+}
+
+// release removes the bucket of size once none of its cells is left, and
+// closes its client unless the tracker still needs it to retry on exit.
+func (t *tuner) release(ctx context.Context, size int) {
+	bk := t.state.Bucket(size)
+	b := t.clients[size]
+	if bk == nil || b == nil {
+		return
+	}
+	var err error
+	if r := t.tracked[size]; r != nil {
+		err = r.Remove(ctx)
+	} else {
+		err = backend.RemoveBucket(context.WithoutCancel(ctx), b, bk.Name)
+	}
+	if err != nil {
+		fmt.Printf("Failed to clean up bucket %s: %v\n", bk.Name, err)
+	}
+	t.state.DropBucket(size)
+	if err == nil || t.tracked[size] == nil {
+		b.Close()
+		delete(t.clients, size)
+	}
+}
+
+// intList returns a flag.Func parser for a comma-separated list of
+// non-negative integers.
+func intList(dst *[]int) func(string) error {
+	return func(s string) error {
+		*dst = nil
+		for _, f := range strings.Split(s, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid value %q: want a non-negative integer", f)
+			}
+			*dst = append(*dst, n)
+		}
+		return nil
+	}
+}
+
+// switchList returns a flag.Func parser for a comma-separated list of "on"
+// and "off", stored as whether the feature is disabled.
+func switchList(dst *[]bool) func(string) error {
+	return func(s string) error {
+		*dst = nil
+		for _, f := range strings.Split(s, ",") {
+			switch strings.TrimSpace(f) {
+			case "on":
+				*dst = append(*dst, false)
+			case "off":
+				*dst = append(*dst, true)
+			default:
+				return fmt.Errorf("invalid value %q: want on or off", f)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package tune
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+// State is the checkpoint of a tuning run, saved after every cell so an
+// interrupted run can be resumed where it stopped.
+type State struct {
+	Backend  string            `json:"backend"`
+	Scenario string            `json:"scenario"`
+	Workload workload.Workload `json:"workload"`
+	Seed     int64             `json:"seed"`
+	Cells    []Cell            `json:"cells"`
+	// Buckets are the buckets loaded for sizes that still have cells to
+	// run. They outlive the program, so a resumed run can reuse them.
+	Buckets []Bucket `json:"buckets,omitempty"`
+	// Done holds the outcome of every cell that completed.
+	Done []Outcome `json:"done"`
+}
+
+// Bucket is the bucket shared by the cells of one size.
+type Bucket struct {
+	Size int    `json:"size"`
+	Name string `json:"name"`
+	// Loaded is set once the load phase filled the bucket.
+	Loaded bool `json:"loaded"`
+}
+
+// LoadState reads the state saved at path.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// Save writes st to path, replacing the previous checkpoint in one step so a
+// crash never leaves half a file behind.
+func (st *State) Save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// Matches reports why st cannot be resumed by a run of other, if it cannot.
+func (st *State) Matches(other *State) error {
+	switch {
+	case st.Backend != other.Backend:
+		return fmt.Errorf("it is for backend %s, not %s", st.Backend, other.Backend)
+	case st.Workload != other.Workload || st.Seed != other.Seed:
+		return errors.New("it is for a different workload or seed")
+	case !slices.Equal(st.Cells, other.Cells):
+		return errors.New("it is for a different matrix")
+	}
+	return nil
+}
+
+// Outcome returns the saved outcome of c, if c completed.
+func (st *State) Outcome(c Cell) (Outcome, bool) {
+	i := slices.IndexFunc(st.Done, func(o Outcome) bool { return o.Cell == c })
+	if i < 0 {
+		return Outcome{}, false
+	}
+	return st.Done[i], true
+}
+
+// Bucket returns the bucket of size, or nil if there is none.
+func (st *State) Bucket(size int) *Bucket {
+	i := slices.IndexFunc(st.Buckets, func(b Bucket) bool { return b.Size == size })
+	if i < 0 {
+		return nil
+	}
+	return &st.Buckets[i]
+}
+
+// DropBucket forgets the bucket of size.
+func (st *State) DropBucket(size int) {
+	st.Buckets = slices.DeleteFunc(st.Buckets, func(b Bucket) bool { return b.Size == size })
+}
+
+// Remaining reports whether any cell of size has yet to complete.
+func (st *State) Remaining(size int) bool {
+	for _, c := range st.Cells {
+		if _, done := st.Outcome(c); c.Size == size && !done {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package tune
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/workload"
+)
+
+func testState() *State {
+	return &State{
+		Backend:  "s3",
+		Scenario: "preset",
+		Workload: workload.Workload{Name: "A", RecordCount: 100, OperationCount: 1000, ObjectSize: 1024},
+		Seed:     1,
+		Cells:    Matrix{Sizes: []int{1024, 4096}, DisableHTTP2: []bool{false, true}}.Cells(),
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(st *State)
+		ok     bool
+	}{
+		{"same", func(st *State) {}, true},
+		{"progress differs", func(st *State) {
+			st.Done = []Outcome{{Cell: st.Cells[0], OpsPerSec: 10}}
+			st.Buckets = []Bucket{{Size: 1024, Name: "b", Loaded: true}}
+		}, true},
+		{"backend", func(st *State) { st.Backend = "tigris" }, false},
+		{"workload", func(st *State) { st.Workload.OperationCount = 2000 }, false},
+		{"seed", func(st *State) { st.Seed = 2 }, false},
+		{"fewer cells", func(st *State) { st.Cells = st.Cells[:2] }, false},
+		{"other transport", func(st *State) { st.Cells[1].Transport = backend.Transport{MaxIdleConnsPerHost: 10} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := testState()
+			tt.change(saved)
+			if err := saved.Matches(testState()); (err == nil) != tt.ok {
+				t.Errorf("Matches = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStateProgress(t *testing.T) {
+	st := testState()
+	st.Done = []Outcome{{Cell: st.Cells[0]}, {Cell: st.Cells[1]}, {Cell: st.Cells[2]}}
+	st.Buckets = []Bucket{{Size: 1024, Name: "small"}, {Size: 4096, Name: "large", Loaded: true}}
+
+	if _, ok := st.Outcome(st.Cells[3]); ok {
+		t.Errorf("Outcome found cell %+v, which has not run", st.Cells[3])
+	}
+	if st.Remaining(1024) || !st.Remaining(4096) {
+		t.Errorf("Remaining = %v for 1024 and %v for 4096, want false and true", st.Remaining(1024), st.Remaining(4096))
+	}
+	if b := st.Bucket(4096); b == nil || b.Name != "large" {
+		t.Errorf("Bucket(4096) = %+v, want large", b)
+	}
+	st.DropBucket(1024)
+	if st.Bucket(1024) != nil || len(st.Buckets) != 1 {
+		t.Errorf("Buckets = %+v after dropping 1024", st.Buckets)
+	}
+}
+
+func TestStateSaveLoad(t *testing.T) {
+	st := testState()
+	st.Done = []Outcome{{Cell: st.Cells[0], Operations: 1000, OpsPerSec: 250.5}}
+	st.Buckets = []Bucket{{Size: 1024, Name: "b", Loaded: true}}
+	path := filepath.Join(t.TempDir(), "tune.json")
+	if err := st.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, st) {
+		t.Errorf("LoadState = %+v, want %+v", got, st)
+	}
+	if err := got.Matches(testState()); err != nil {
+		t.Errorf("saved state does not match its run: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package tune runs one workload across a matrix of HTTP transport settings
+// and object sizes, and picks the transport that moves the most data at each
+// size.
+package tune
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/runner"
+)
+
+// Matrix lists the values to try for each knob. An empty list tries only
+// the SDK default.
+type Matrix struct {
+	Sizes               []int
+	MaxIdleConnsPerHost []int
+	DisableKeepAlives   []bool
+	DisableHTTP2        []bool
+	ReadBufferSize      []int
+	WriteBufferSize     []int
+	DisableCompression  []bool
+}
+
+// Cell is one combination of the matrix.
+type Cell struct {
+	Size      int               `json:"size"`
+	Transport backend.Transport `json:"transport"`
+}
+
+// Cells returns every combination of m, grouped by size.
+func (m Matrix) Cells() []Cell {
+	orZero := func(v []int) []int {
+		if len(v) == 0 {
+			return []int{0}
+		}
+		return v
+	}
+	orFalse := func(v []bool) []bool {
+		if len(v) == 0 {
+			return []bool{false}
+		}
+		return v
+	}
+	var cells []Cell
+	for _, size := range orZero(m.Sizes) {
+		for _, idle := range orZero(m.MaxIdleConnsPerHost) {
+			for _, noKeepAlive := range orFalse(m.DisableKeepAlives) {
+				for _, noHTTP2 := range orFalse(m.DisableHTTP2) {
+					for _, rbuf := range orZero(m.ReadBufferSize) {
+						for _, wbuf := range orZero(m.WriteBufferSize) {
+							for _, noGzip := range orFalse(m.DisableCompression) {
+								cells = append(cells, Cell{Size: size, Transport: backend.Transport{
+									MaxIdleConnsPerHost: idle,
+									DisableKeepAlives:   noKeepAlive,
+									DisableHTTP2:        noHTTP2,
+									ReadBufferSize:      rbuf,
+									WriteBufferSize:     wbuf,
+									DisableCompression:  noGzip,
+								}})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// Outcome is the throughput one cell reached in the run phase. Err is set
+// instead when the cell could not be run, and Incomplete when it was
+// stopped before its run phase finished.
+type Outcome struct {
+	Cell
+	Operations int     `json:"operations"`
+	Errors     int     `json:"errors"`
+	Timeouts   int     `json:"timeouts,omitempty"`
+	OpsPerSec  float64 `json:"ops_per_sec"`
+	GBPerSec   float64 `json:"gb_per_sec"`
+	Incomplete bool    `json:"incomplete,omitempty"`
+	Err        string  `json:"error,omitempty"`
+}
+
+// Measure returns the outcome of c from the run phase of res. Throughput
+// covers successful operations over the phase's wall-clock duration.
+func Measure(c Cell, res *runner.Result) Outcome {
+	o := Outcome{Cell: c, Incomplete: res.Interrupted}
+	for _, ph := range res.Phases {
+		o.Incomplete = o.Incomplete || ph.Incomplete
+		if ph.Name != runner.PhaseRun || ph.DurationMs <= 0 {
+			continue
+		}
+		var bytes int64
+		for _, s := range ph.Operations {
+			if s.Variant != "" {
+				continue
+			}
+			o.Operations += s.Count
+			o.Errors += s.Errors
+			o.Timeouts += s.Timeouts
+			bytes += s.Bytes
+		}
+		secs := ph.DurationMs / 1000
+		o.OpsPerSec = float64(o.Operations) / secs
+		o.GBPerSec = float64(bytes) / secs / 1e9
+	}
+	return o
+}
+
+// Best returns the cell with the highest throughput at each size, in the
+// order sizes first appear. Cells that failed or are incomplete are never
+// picked.
+func Best(outcomes []Outcome) []Outcome {
+	var best []Outcome
+	for _, o := range outcomes {
+		if o.Err != "" || o.Incomplete {
+			continue
+		}
+		i := slices.IndexFunc(best, func(b Outcome) bool { return b.Size == o.Size })
+		switch {
+		case i < 0:
+			best = append(best, o)
+		case o.OpsPerSec > best[i].OpsPerSec:
+			best[i] = o
+		}
+	}
+	return best
+}
+
+// Report is the structured record of a tuning run, written with --out.
+type Report struct {
+	Backend  string    `json:"backend"`
+	Scenario string    `json:"scenario"`
+	Outcomes []Outcome `json:"outcomes"`
+	Best     []Outcome `json:"best"`
+	// Interrupted is set if a signal stopped the run before every cell
+	// was measured.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// WriteFile writes the report as indented JSON to path.
+func (rep Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// PrintOutcome prints one cell's line as it finishes.
+func PrintOutcome(out io.Writer, i, n int, o Outcome) {
+	if o.Err != "" {
+		fmt.Fprintf(out, "Cell %d/%d size=%d %s: failed: %s\n", i, n, o.Size, o.Transport, o.Err)
+		return
+	}
+	var note string
+	if o.Incomplete {
+		note = " (incomplete)"
+	}
+	fmt.Fprintf(out, "Cell %d/%d size=%d %s: %.1f ops/sec, %.4f GB/sec, %d errors, %d timeouts%s\n",
+		i, n, o.Size, o.Transport, o.OpsPerSec, o.GBPerSec, o.Errors, o.Timeouts, note)
+}
+
+// Print prints the best transport for each size.
+func (rep Report) Print(out io.Writer) {
+	fmt.Fprintf(out, "\n===== BEST TRANSPORT PER SIZE =====\n")
+	if rep.Interrupted {
+		fmt.Fprintf(out, "Interrupted after %d cells; incomplete cells are not compared\n", len(rep.Outcomes))
+	}
+	if len(rep.Best) == 0 {
+		fmt.Fprintf(out, "No cell completed\n")
+		return
+	}
+	fmt.Fprintf(out, "%-12s %12s %12s  %s\n", "Size", "Ops/sec", "GB/sec", "Transport")
+	for _, o := range rep.Best {
+		fmt.Fprintf(out, "%-12d %12.1f %12.4f  %s\n", o.Size, o.OpsPerSec, o.GBPerSec, o.Transport)
+	}
+}
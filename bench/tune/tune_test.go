@@ -0,0 +1,113 @@
+package tune
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/backend"
+)
+
+func TestCells(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Matrix
+		want []Cell
+	}{
+		{
+			name: "empty",
+			want: []Cell{{}},
+		},
+		{
+			name: "sizes only",
+			m:    Matrix{Sizes: []int{1024, 4096}},
+			want: []Cell{{Size: 1024}, {Size: 4096}},
+		},
+		{
+			name: "grouped by size",
+			m: Matrix{
+				Sizes:               []int{1024, 4096},
+				MaxIdleConnsPerHost: []int{10, 100},
+				DisableHTTP2:        []bool{false, true},
+			},
+			want: []Cell{
+				{Size: 1024, Transport: backend.Transport{MaxIdleConnsPerHost: 10}},
+				{Size: 1024, Transport: backend.Transport{MaxIdleConnsPerHost: 10, DisableHTTP2: true}},
+				{Size: 1024, Transport: backend.Transport{MaxIdleConnsPerHost: 100}},
+				{Size: 1024, Transport: backend.Transport{MaxIdleConnsPerHost: 100, DisableHTTP2: true}},
+				{Size: 4096, Transport: backend.Transport{MaxIdleConnsPerHost: 10}},
+				{Size: 4096, Transport: backend.Transport{MaxIdleConnsPerHost: 10, DisableHTTP2: true}},
+				{Size: 4096, Transport: backend.Transport{MaxIdleConnsPerHost: 100}},
+				{Size: 4096, Transport: backend.Transport{MaxIdleConnsPerHost: 100, DisableHTTP2: true}},
+			},
+		},
+		{
+			name: "every knob",
+			m: Matrix{
+				Sizes:               []int{1},
+				MaxIdleConnsPerHost: []int{1},
+				DisableKeepAlives:   []bool{true},
+				DisableHTTP2:        []bool{true},
+				ReadBufferSize:      []int{2},
+				WriteBufferSize:     []int{3},
+				DisableCompression:  []bool{true},
+			},
+			want: []Cell{{Size: 1, Transport: backend.Transport{
+				MaxIdleConnsPerHost: 1,
+				DisableKeepAlives:   true,
+				DisableHTTP2:        true,
+				ReadBufferSize:      2,
+				WriteBufferSize:     3,
+				DisableCompression:  true,
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Cells(); !slices.Equal(got, tt.want) {
+				t.Errorf("Cells = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	outcome := func(size, idle int, opsPerSec float64) Outcome {
+		return Outcome{Cell: Cell{Size: size, Transport: backend.Transport{MaxIdleConnsPerHost: idle}}, OpsPerSec: opsPerSec}
+	}
+	failed := outcome(1024, 3, 900)
+	failed.Err = "connection refused"
+	incomplete := outcome(1024, 4, 800)
+	incomplete.Incomplete = true
+	onlyFailed := outcome(8192, 1, 50)
+	onlyFailed.Err = "timeout"
+
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		want     []Outcome
+	}{
+		{"none", nil, nil},
+		{
+			name:     "fastest per size in order of appearance",
+			outcomes: []Outcome{outcome(4096, 1, 10), outcome(1024, 1, 100), outcome(4096, 2, 30), outcome(1024, 2, 50)},
+			want:     []Outcome{outcome(4096, 2, 30), outcome(1024, 1, 100)},
+		},
+		{
+			name:     "tie keeps the first",
+			outcomes: []Outcome{outcome(1024, 1, 100), outcome(1024, 2, 100)},
+			want:     []Outcome{outcome(1024, 1, 100)},
+		},
+		{
+			name:     "failed and incomplete cells never win",
+			outcomes: []Outcome{outcome(1024, 1, 100), failed, incomplete, onlyFailed},
+			want:     []Outcome{outcome(1024, 1, 100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Best(tt.outcomes); !slices.Equal(got, tt.want) {
+				t.Errorf("Best = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package workload
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// Distribution names accepted in Workload.Distribution.
+const (
+	DistUniform = "uniform"
+	DistZipfian = "zipfian"
+	DistHotspot = "hotspot"
+	DistLatest  = "latest"
+)
+
+// Default distribution parameters, matching the YCSB defaults.
+const (
+	DefaultZipfTheta = 0.99
+	DefaultHotKeys   = 0.2
+	DefaultHotOps    = 0.8
+)
+
+// Distributions returns the accepted distribution names.
+func Distributions() []string {
+	return []string{DistUniform, DistZipfian, DistHotspot, DistLatest}
+}
+
+// Chooser picks which existing record an operation targets. Implementations
+// are safe for concurrent use.
+type Chooser interface {
+	// Next returns a record number in [0, n).
+	Next(r *rand.Rand, n int64) int64
+}
+
+// NewChooser returns the chooser for w's key distribution:
+//
+//	uniform  every written record is equally likely
+//	zipfian  popularity follows a Zipf law with skew ZipfTheta; popular
+//	         records are scattered over the key space
+//	hotspot  HotOps of the operations go to the first HotKeys of the records
+//	latest   Zipf-distributed over recency, so the newest records are hottest
+//
+// Zero parameters take the Default values.
+func NewChooser(w Workload) (Chooser, error) {
+	theta := w.ZipfTheta
+	if theta == 0 {
+		theta = DefaultZipfTheta
+	}
+	hotKeys, hotOps := w.HotKeys, w.HotOps
+	if hotKeys == 0 {
+		hotKeys = DefaultHotKeys
+	}
+	if hotOps == 0 {
+		hotOps = DefaultHotOps
+	}
+
+	switch w.Distribution {
+	case DistUniform, "":
+		return uniform{}, nil
+	case DistZipfian:
+		if theta <= 0 || theta >= 1 {
+			return nil, fmt.Errorf("zipf theta must be in (0, 1), got %g", theta)
+		}
+		return scrambled{newZipfian(theta)}, nil
+	case DistHotspot:
+		if hotKeys <= 0 || hotKeys > 1 || hotOps < 0 || hotOps > 1 {
+			return nil, fmt.Errorf("hotspot fractions must be in (0, 1], got keys=%g ops=%g", hotKeys, hotOps)
+		}
+		return hotspot{keys: hotKeys, ops: hotOps}, nil
+	case DistLatest:
+		if theta <= 0 || theta >= 1 {
+			return nil, fmt.Errorf("zipf theta must be in (0, 1), got %g", theta)
+		}
+		return latest{newZipfian(theta)}, nil
+	default:
+		return nil, fmt.Errorf("unknown key distribution %q (available: %v)", w.Distribution, Distributions())
+	}
+}
+
+type uniform struct{}
+
+func (uniform) Next(r *rand.Rand, n int64) int64 {
+	return r.Int63n(n)
+}
+
+type hotspot struct {
+	keys, ops float64
+}
+
+func (h hotspot) Next(r *rand.Rand, n int64) int64 {
+	hot := int64(float64(n) * h.keys)
+	if hot < 1 {
+		hot = 1
+	}
+	if r.Float64() < h.ops || hot >= n {
+		return r.Int63n(hot)
+	}
+	return hot + r.Int63n(n-hot)
+}
+
+// zipfian draws ranks from a Zipf distribution using the method of Gray et
+// al., "Quickly Generating Billion-Record Synthetic Databases" (SIGMOD 1994),
+// as YCSB does. Rank 0 is the most popular. The zeta constant is extended
+// incrementally as inserts grow the key space.
+type zipfian struct {
+	theta float64
+	alpha float64
+	zeta2 float64
+
+	mu    sync.Mutex
+	n     int64
+	zetan float64
+}
+
+func newZipfian(theta float64) *zipfian {
+	return &zipfian{
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zeta2: 1 + math.Pow(0.5, theta),
+	}
+}
+
+// zeta returns the zeta constant for n items, extending the cached sum.
+// The cache only grows: workers that last saw the key space before another
+// worker's insert ask for a slightly smaller n, which is answered by taking
+// the few extra terms off the cached sum.
+func (z *zipfian) zeta(n int64) float64 {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for ; z.n < n; z.n++ {
+		z.zetan += 1 / math.Pow(float64(z.n+1), z.theta)
+	}
+	zetan := z.zetan
+	for i := z.n; i > n; i-- {
+		zetan -= 1 / math.Pow(float64(i), z.theta)
+	}
+	return zetan
+}
+
+func (z *zipfian) Next(r *rand.Rand, n int64) int64 {
+	if n <= 1 {
+		return 0
+	}
+	zetan := z.zeta(n)
+	eta := (1 - math.Pow(2/float64(n), 1-z.theta)) / (1 - z.zeta2/zetan)
+
+	u := r.Float64()
+	uz := u * zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < z.zeta2 {
+		return 1
+	}
+	rank := int64(float64(n) * math.Pow(eta*u-eta+1, z.alpha))
+	if rank >= n {
+		rank = n - 1
+	}
+	return rank
+}
+
+// scrambled spreads Zipf ranks over the key space with a hash, so the hot
+// records are not all neighbours sharing one scan prefix.
+type scrambled struct {
+	z *zipfian
+}
+
+func (s scrambled) Next(r *rand.Rand, n int64) int64 {
+	rank := s.z.Next(r, n)
+	h := fnv.New64a()
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = byte(rank >> (8 * i))
+	}
+	h.Write(buf[:])
+	return int64(h.Sum64() % uint64(n))
+}
+
+// latest counts Zipf ranks back from the newest record.
+type latest struct {
+	z *zipfian
+}
+
+func (l latest) Next(r *rand.Rand, n int64) int64 {
+	return n - 1 - l.z.Next(r, n)
+}
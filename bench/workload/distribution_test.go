@@ -0,0 +1,132 @@
+package workload
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestChooserRange(t *testing.T) {
+	for _, dist := range Distributions() {
+		c, err := NewChooser(Workload{Distribution: dist})
+		if err != nil {
+			t.Fatalf("%s: %v", dist, err)
+		}
+		r := rand.New(rand.NewSource(1))
+		for _, n := range []int64{1, 2, 3, 10, 1000} {
+			for range 10000 {
+				if k := c.Next(r, n); k < 0 || k >= n {
+					t.Fatalf("%s: Next(%d) = %d, want in [0, %d)", dist, n, k, n)
+				}
+			}
+		}
+	}
+}
+
+// topShare returns the share of draws that went to the most drawn tenth of
+// the n keys, and the counts by key.
+func topShare(c Chooser, n int64, draws int) (float64, []int) {
+	r := rand.New(rand.NewSource(1))
+	counts := make([]int, n)
+	for range draws {
+		counts[c.Next(r, n)]++
+	}
+	sorted := slices.Clone(counts)
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+	top := 0
+	for _, c := range sorted[:n/10] {
+		top += c
+	}
+	return float64(top) / float64(draws), counts
+}
+
+func TestChooserSkew(t *testing.T) {
+	const n, draws = 1000, 200000
+	tests := []struct {
+		name     string
+		w        Workload
+		min, max float64
+	}{
+		{"uniform", Workload{Distribution: DistUniform}, 0.10, 0.12},
+		// 80% of the draws spread over 200 hot keys, half of them in the top 100.
+		{"hotspot", Workload{Distribution: DistHotspot}, 0.39, 0.42},
+		{"hotspot 10/90", Workload{Distribution: DistHotspot, HotKeys: 0.1, HotOps: 0.9}, 0.89, 0.91},
+		{"zipfian", Workload{Distribution: DistZipfian}, 0.60, 0.80},
+		{"zipfian theta 0.5", Workload{Distribution: DistZipfian, ZipfTheta: 0.5}, 0.25, 0.40},
+		{"latest", Workload{Distribution: DistLatest}, 0.60, 0.80},
+	}
+	for _, tt := range tests {
+		c, err := NewChooser(tt.w)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		share, _ := topShare(c, n, draws)
+		if share < tt.min || share > tt.max {
+			t.Errorf("%s: top tenth of keys got %.3f of draws, want in [%.2f, %.2f]", tt.name, share, tt.min, tt.max)
+		}
+	}
+}
+
+func TestChooserHotKeys(t *testing.T) {
+	const n, draws = 1000, 100000
+	tests := []struct {
+		dist string
+		// hot returns whether key k is among the keys the distribution
+		// favours.
+		hot  func(k int) bool
+		want float64
+	}{
+		{DistHotspot, func(k int) bool { return k < n*DefaultHotKeys }, DefaultHotOps},
+		{DistLatest, func(k int) bool { return k >= n-n/10 }, 0.6},
+	}
+	for _, tt := range tests {
+		c, err := NewChooser(Workload{Distribution: tt.dist})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, counts := topShare(c, n, draws)
+		hot := 0
+		for k, c := range counts {
+			if tt.hot(k) {
+				hot += c
+			}
+		}
+		if share := float64(hot) / draws; share < tt.want {
+			t.Errorf("%s: favoured keys got %.3f of draws, want at least %.2f", tt.dist, share, tt.want)
+		}
+	}
+}
+
+func TestNewChooserRejects(t *testing.T) {
+	tests := []Workload{
+		{Distribution: "pareto"},
+		{Distribution: DistZipfian, ZipfTheta: 1},
+		{Distribution: DistZipfian, ZipfTheta: -0.5},
+		{Distribution: DistLatest, ZipfTheta: 1.5},
+		{Distribution: DistHotspot, HotKeys: 1.5},
+		{Distribution: DistHotspot, HotOps: -0.1},
+	}
+	for _, w := range tests {
+		if _, err := NewChooser(w); err == nil {
+			t.Errorf("NewChooser(%+v) succeeded, want an error", w)
+		}
+	}
+}
+
+func TestZetaCache(t *testing.T) {
+	// Key spaces seen by workers racing inserts, growing and shrinking.
+	ns := []int64{10, 500, 499, 2, 1000, 998, 1000, 1500}
+	z := newZipfian(DefaultZipfTheta)
+	for _, n := range ns {
+		got := z.zeta(n)
+		want := newZipfian(DefaultZipfTheta).zeta(n)
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("zeta(%d) = %v after earlier calls, want %v", n, got, want)
+		}
+	}
+	if z.n != slices.Max(ns) {
+		t.Errorf("cached up to %d, want %d", z.n, slices.Max(ns))
+	}
+}
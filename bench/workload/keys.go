@@ -0,0 +1,74 @@
+package workload
+
+import (
+	"fmt"
+	"sync"
+)
+
+// keyDigits is the zero-padded width of the record number in a key. Keys sort
+// in record order, so dropping trailing digits gives a prefix that covers a
+// contiguous range of records.
+const keyDigits = 10
+
+// Key returns the object key for record i.
+func Key(i int64) string {
+	return fmt.Sprintf("user%0*d", keyDigits, i)
+}
+
+// ScanPrefix returns the ListObjects prefix for a short range scan starting
+// at record i. Dropping the last digit covers record i and up to nine of its
+// neighbours.
+func ScanPrefix(i int64) string {
+	k := Key(i)
+	return k[:len(k)-1]
+}
+
+// KeySpace hands out record numbers for inserts and tracks which of them have
+// been written, so reads only target keys that exist. It is safe for
+// concurrent use.
+type KeySpace struct {
+	mu    sync.Mutex
+	next  int64
+	limit int64
+	acked map[int64]bool
+}
+
+// NewKeySpace returns a key space whose records start at zero.
+func NewKeySpace() *KeySpace {
+	return &KeySpace{acked: make(map[int64]bool)}
+}
+
+// NewKeySpaceAt returns a key space whose first n records already exist, such
+// as a bucket loaded by an earlier run.
+func NewKeySpaceAt(n int64) *KeySpace {
+	return &KeySpace{next: n, limit: n, acked: make(map[int64]bool)}
+}
+
+// Allocate reserves the next record number for an insert.
+func (ks *KeySpace) Allocate() int64 {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	i := ks.next
+	ks.next++
+	return i
+}
+
+// Ack marks record i as written. Records become visible to Limit in order, so
+// an insert that finishes early stays hidden until those before it finish.
+func (ks *KeySpace) Ack(i int64) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.acked[i] = true
+	for ks.acked[ks.limit] {
+		delete(ks.acked, ks.limit)
+		ks.limit++
+	}
+}
+
+// Limit returns the number of records known to be written; every record
+// below it exists.
+func (ks *KeySpace) Limit() int64 {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.limit
+}
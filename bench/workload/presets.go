@@ -0,0 +1,95 @@
+package workload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default sizing shared by every preset. Keeping these fixed is what makes
+// runs of the same preset comparable; override them on the command line only
+// for exploratory runs.
+const (
+	DefaultRecordCount    = 1000
+	DefaultOperationCount = 1000
+	DefaultObjectSize     = 1024 // 1KB
+	DefaultThreads        = 1
+)
+
+// presets are the YCSB core workloads A-F adapted to object storage. A record
+// is one object, a field update is a whole-object PutObject, and a scan is a
+// ListObjects call on a key prefix.
+var presets = map[string]Workload{
+	"A": {
+		Name:  "A",
+		Title: "Update heavy",
+		Description: "50% reads, 50% overwrites of existing objects. " +
+			"Models a session store recording recent actions.",
+		Mix:          Mix{Read: 0.5, Update: 0.5},
+		Distribution: DistUniform,
+	},
+	"B": {
+		Name:  "B",
+		Title: "Read mostly",
+		Description: "95% reads, 5% overwrites of existing objects. " +
+			"Models photo tagging: tags are added rarely, read often.",
+		Mix:          Mix{Read: 0.95, Update: 0.05},
+		Distribution: DistUniform,
+	},
+	"C": {
+		Name:  "C",
+		Title: "Read only",
+		Description: "100% reads. " +
+			"Models a user profile cache built elsewhere.",
+		Mix:          Mix{Read: 1},
+		Distribution: DistUniform,
+	},
+	"D": {
+		Name:  "D",
+		Title: "Read latest",
+		Description: "95% reads, 5% inserts of new objects; reads favour the newest objects. " +
+			"Models user status updates where people read the latest posts.",
+		Mix:          Mix{Read: 0.95, Insert: 0.05},
+		Distribution: DistLatest,
+	},
+	"E": {
+		Name:  "E",
+		Title: "Short ranges",
+		Description: "95% short scans, 5% inserts. A scan lists one key prefix " +
+			"covering up to ten neighbouring objects. Models threaded conversations.",
+		Mix:          Mix{Scan: 0.95, Insert: 0.05},
+		Distribution: DistUniform,
+	},
+	"F": {
+		Name:  "F",
+		Title: "Read-modify-write",
+		Description: "50% reads, 50% read-modify-write cycles (GetObject then PutObject of the same key). " +
+			"Models a user database where records are read, changed and written back.",
+		Mix:          Mix{Read: 0.5, ReadModifyWrite: 0.5},
+		Distribution: DistUniform,
+	},
+}
+
+// Preset returns the named preset with the default sizing applied. Names are
+// case-insensitive.
+func Preset(name string) (Workload, error) {
+	w, ok := presets[strings.ToUpper(name)]
+	if !ok {
+		return Workload{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	w.RecordCount = DefaultRecordCount
+	w.OperationCount = DefaultOperationCount
+	w.ObjectSize = DefaultObjectSize
+	w.Threads = DefaultThreads
+	return w, nil
+}
+
+// PresetNames returns the sorted names of all presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,134 @@
+// Package workload describes the operation mixes the runner executes and the
+// named presets built from them.
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Op is a single kind of operation in a workload mix.
+type Op string
+
+const (
+	// OpRead fetches an existing object with GetObject.
+	OpRead Op = "Read"
+	// OpUpdate overwrites an existing object with PutObject.
+	OpUpdate Op = "Update"
+	// OpInsert writes a new object with PutObject.
+	OpInsert Op = "Insert"
+	// OpScan lists a short range of keys with ListObjects and a key prefix.
+	OpScan Op = "Scan"
+	// OpReadModifyWrite reads an existing object and writes it back.
+	OpReadModifyWrite Op = "Read-Modify-Write"
+)
+
+// Mix holds the proportion of each operation in the run phase. Proportions
+// are relative weights and need not sum to one.
+type Mix struct {
+	Read            float64 `json:"read,omitempty"`
+	Update          float64 `json:"update,omitempty"`
+	Insert          float64 `json:"insert,omitempty"`
+	Scan            float64 `json:"scan,omitempty"`
+	ReadModifyWrite float64 `json:"read_modify_write,omitempty"`
+}
+
+type weighted struct {
+	op     Op
+	weight float64
+}
+
+func (m Mix) entries() []weighted {
+	return []weighted{
+		{OpRead, m.Read},
+		{OpUpdate, m.Update},
+		{OpInsert, m.Insert},
+		{OpScan, m.Scan},
+		{OpReadModifyWrite, m.ReadModifyWrite},
+	}
+}
+
+func (m Mix) total() float64 {
+	var total float64
+	for _, e := range m.entries() {
+		total += e.weight
+	}
+	return total
+}
+
+// Shares returns the fraction of operations expected to be each operation in
+// the mix. Operations with no weight are omitted.
+func (m Mix) Shares() map[Op]float64 {
+	total := m.total()
+	shares := make(map[Op]float64)
+	for _, e := range m.entries() {
+		if e.weight > 0 {
+			shares[e.op] = e.weight / total
+		}
+	}
+	return shares
+}
+
+// Choose picks an operation with probability proportional to its weight.
+func (m Mix) Choose(r *rand.Rand) Op {
+	x := r.Float64() * m.total()
+	var last Op
+	for _, e := range m.entries() {
+		if e.weight <= 0 {
+			continue
+		}
+		if x < e.weight {
+			return e.op
+		}
+		x -= e.weight
+		last = e.op
+	}
+	return last
+}
+
+// Workload is a complete description of a benchmark run: how many objects to
+// load, how many operations to issue afterwards, and in what mix.
+type Workload struct {
+	Name           string `json:"name"`
+	Title          string `json:"title"`
+	Description    string `json:"description,omitempty"`
+	RecordCount    int    `json:"record_count"`
+	OperationCount int    `json:"operation_count"`
+	ObjectSize     int    `json:"object_size"`
+	Threads        int    `json:"threads"`
+	Mix            Mix    `json:"mix"`
+	// Distribution selects which existing keys reads, updates and scans
+	// target; see NewChooser for the names and their parameters.
+	Distribution string  `json:"distribution"`
+	ZipfTheta    float64 `json:"zipf_theta,omitempty"`
+	HotKeys      float64 `json:"hot_keys,omitempty"`
+	HotOps       float64 `json:"hot_ops,omitempty"`
+}
+
+// Validate reports whether w can be run.
+func (w Workload) Validate() error {
+	if w.RecordCount <= 0 {
+		return fmt.Errorf("record count must be positive, got %d", w.RecordCount)
+	}
+	if w.OperationCount < 0 {
+		return fmt.Errorf("operation count must not be negative, got %d", w.OperationCount)
+	}
+	if w.ObjectSize < 0 {
+		return fmt.Errorf("object size must not be negative, got %d", w.ObjectSize)
+	}
+	if w.Threads <= 0 {
+		return fmt.Errorf("threads must be positive, got %d", w.Threads)
+	}
+	for _, e := range w.Mix.entries() {
+		if e.weight < 0 {
+			return fmt.Errorf("proportion for %s must not be negative", e.op)
+		}
+	}
+	if w.OperationCount > 0 && w.Mix.total() == 0 {
+		return fmt.Errorf("workload %s has an empty operation mix", w.Name)
+	}
+	if _, err := NewChooser(w); err != nil {
+		return err
+	}
+	return nil
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"sort"
 	"strconv"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -90,29 +92,24 @@ func calculateMetrics(latencies []time.Duration, operation string, dataSize int6
 }
 
 func main() {
+	// Remove everything the test creates, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
 	region := "us-east-1"
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
 	if err != nil {
-		log.Fatalf("Unable to load SDK config, %v", err)
+		log.Printf("Unable to load SDK config, %v", err)
+		tracker.Exit(1)
 	}
 
 	s3Client := s3.NewFromConfig(cfg)
-	ctx := context.TODO()
 
 	// Create a unique bucket name for Express One Zone
 	baseName := "go-express-bucket-" + strconv.FormatInt(time.Now().UnixNano(), 10)
 	zoneID := "use1-az6" // Zone ID for us-east-1c
 	bucketName := fmt.Sprintf("%s--%s--x-s3", baseName, zoneID)
 
-	var bucketCreated bool
-
-	// Ensure bucket cleanup happens even on errors after creation
-	defer func() {
-		if bucketCreated {
-			cleanupBucket(ctx, s3Client, bucketName)
-		}
-	}()
-
 	// Create Express One Zone directory bucket
 	fmt.Printf("Creating Express One Zone directory bucket: %s\n", bucketName)
 	_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{
@@ -129,11 +126,22 @@ func main() {
 		},
 	})
 	if err != nil {
-		log.Fatalf("Failed to create bucket %s: %v", bucketName, err)
+		log.Printf("Failed to create bucket %s: %v", bucketName, err)
+		tracker.Exit(1)
 	}
-	bucketCreated = true
 	fmt.Printf("Successfully created bucket %s\n", bucketName)
 
+	// Ensure bucket cleanup happens even on errors after creation
+	bucket := tracker.Track(cleanup.Bucket, bucketName, cleanup.S3Bucket(s3Client, bucketName))
+	defer func() {
+		fmt.Printf("\nCleaning up bucket: %s\n", bucketName)
+		if err := bucket.Remove(ctx); err != nil {
+			log.Printf("Failed to clean up bucket %s: %v", bucketName, err)
+		} else {
+			fmt.Println("Successfully deleted bucket.")
+		}
+	}()
+
 	// Allow some time for bucket creation to propagate (optional but can help prevent immediate errors)
 	// time.Sleep(5 * time.Second)
 
@@ -213,7 +221,7 @@ func main() {
 	}
 
 	// --- Step 3: Delete objects ---
-	// Note: Cleanup function called via defer handles deletion,
+	// Note: Cleanup registered via defer handles deletion,
 	// but we can measure individual deletes here if needed.
 	fmt.Println("\nStarting delete operations...")
 	for _, size := range objectSizes {
@@ -239,62 +247,3 @@ func main() {
 		calculateMetrics(deleteLatencies, fmt.Sprintf("Delete (Size: %d bytes)", size), 0) // dataSize = 0 for delete
 	}
 }
-
-// cleanupBucket deletes all objects in the bucket and then deletes the bucket itself.
-func cleanupBucket(ctx context.Context, s3Client *s3.Client, bucketName string) {
-	fmt.Printf("\nCleaning up bucket: %s\n", bucketName)
-
-	// List and delete all objects
-	var objectsToDelete []types.ObjectIdentifier
-	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucketName),
-	})
-
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			log.Printf("Failed to list objects for cleanup in %s: %v", bucketName, err)
-			// Proceed to try deleting the bucket anyway, might fail if not empty
-			break
-		}
-		for _, obj := range page.Contents {
-			objectsToDelete = append(objectsToDelete, types.ObjectIdentifier{Key: obj.Key})
-		}
-	}
-
-	// Batch delete objects (up to 1000 per request)
-	if len(objectsToDelete) > 0 {
-		// S3 batch delete takes max 1000 keys
-		chunkSize := 1000
-		for i := 0; i < len(objectsToDelete); i += chunkSize {
-			end := i + chunkSize
-			if end > len(objectsToDelete) {
-				end = len(objectsToDelete)
-			}
-			chunk := objectsToDelete[i:end]
-
-			fmt.Printf("Deleting %d objects...\n", len(chunk))
-			_, err := s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
-				Bucket: aws.String(bucketName),
-				Delete: &types.Delete{Objects: chunk},
-			})
-			if err != nil {
-				log.Printf("Failed to delete object chunk in %s: %v", bucketName, err)
-				// Continue trying to delete other chunks/bucket
-			}
-		}
-	} else {
-		fmt.Println("No objects found to delete.")
-	}
-
-	// Delete bucket
-	fmt.Println("Deleting bucket...")
-	_, err := s3Client.DeleteBucket(ctx, &s3.DeleteBucketInput{
-		Bucket: aws.String(bucketName),
-	})
-	if err != nil {
-		log.Printf("Failed to delete bucket %s: %v", bucketName, err)
-	} else {
-		fmt.Println("Successfully deleted bucket.")
-	}
-}
@@ -10,6 +10,8 @@ import (
 	"sort"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/progress"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -20,27 +22,29 @@ func main() {
 	fmt.Println("AWS S3 SDK Benchmark - Test Suite 2")
 	fmt.Println("===================================")
 
+	// Remove everything the tests create, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
 	// Run large object test
-	largeObjectTest()
+	largeObjectTest(ctx, tracker)
 
 	// Run list operations test
-	listOperationsTest()
+	listOperationsTest(ctx, tracker)
 }
 
 // largeObjectTest tests operations with a large 10GB object
-func largeObjectTest() {
+func largeObjectTest(ctx context.Context, tracker *cleanup.Tracker) {
 	fmt.Println("\n===== LARGE OBJECT TEST =====")
 
 	// Initialize client
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("us-east-1"))
 	if err != nil {
 		fmt.Printf("Failed to load AWS config: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	client := s3.NewFromConfig(cfg)
 
-	ctx := context.Background()
-
 	// Create test bucket
 	bucketName := fmt.Sprintf("large-object-test-%d", time.Now().UnixNano())
 	fmt.Printf("\nCreating bucket: %s\n", bucketName)
@@ -52,14 +56,12 @@ func largeObjectTest() {
 		return
 	}
 
+	bucket := tracker.Track(cleanup.Bucket, bucketName, cleanup.S3Bucket(client, bucketName))
 	defer func() {
-		// Clean up bucket at the end
+		// Clean up bucket at the end, including any unfinished upload
 		fmt.Printf("\nCleaning up bucket: %s\n", bucketName)
-		_, err = client.DeleteBucket(ctx, &s3.DeleteBucketInput{
-			Bucket: aws.String(bucketName),
-		})
-		if err != nil {
-			fmt.Printf("Failed to delete bucket: %v\n", err)
+		if err := bucket.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
 		}
 	}()
 
@@ -88,12 +90,16 @@ func largeObjectTest() {
 		fmt.Printf("Failed to initialize multipart upload: %v\n", err)
 		return
 	}
+	upload := bucket.Track(cleanup.Upload, key, cleanup.S3Upload(client, bucketName, key, aws.ToString(createOutput.UploadId)))
 
 	// Split data into chunks of 100MB
 	chunkSize := int64(100 * 1024 * 1024) // 100MB chunks
 	var completedParts []types.CompletedPart
 	buffer := make([]byte, chunkSize)
 
+	numParts := int((objectSize + chunkSize - 1) / chunkSize)
+	partProgress := progress.Start("Multipart Upload", numParts)
+
 	var partNumber int32 = 1
 	for offset := int64(0); offset < objectSize; offset += chunkSize {
 		// Calculate the size of the current chunk
@@ -106,6 +112,7 @@ func largeObjectTest() {
 		n := copy(buffer, data[offset:offset+currentChunkSize])
 
 		// Upload part
+		partStart := time.Now()
 		partOutput, err := client.UploadPart(ctx, &s3.UploadPartInput{
 			Bucket:     aws.String(bucketName),
 			Key:        aws.String(key),
@@ -113,14 +120,11 @@ func largeObjectTest() {
 			UploadId:   createOutput.UploadId,
 			Body:       bytes.NewReader(buffer[:n]),
 		})
+		partProgress.Observe(time.Since(partStart), int64(n), err)
 		if err != nil {
+			partProgress.Finish()
 			// Abort multipart upload on failure
-			_, abortErr := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
-				Bucket:   aws.String(bucketName),
-				Key:      aws.String(key),
-				UploadId: createOutput.UploadId,
-			})
-			if abortErr != nil {
+			if abortErr := upload.Remove(ctx); abortErr != nil {
 				fmt.Printf("Failed to abort multipart upload: %v\n", abortErr)
 			}
 			fmt.Printf("Failed to upload part %d: %v\n", partNumber, err)
@@ -132,9 +136,9 @@ func largeObjectTest() {
 			ETag:       partOutput.ETag,
 		})
 
-		fmt.Printf("Uploaded part %d (size: %.2f MB)\n", partNumber, float64(n)/(1024*1024))
 		partNumber++
 	}
+	partProgress.Finish()
 
 	// Complete multipart upload
 	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
@@ -149,26 +153,32 @@ func largeObjectTest() {
 		fmt.Printf("Failed to complete multipart upload: %v\n", err)
 		return
 	}
+	upload.Forget()
+	object := bucket.Track(cleanup.Object, key, cleanup.S3Object(client, bucketName, key))
 
 	uploadLatency := time.Since(startTime)
 	calculateMetrics([]time.Duration{uploadLatency}, "Large Object Upload (Multipart)", int(objectSize))
 
 	// Read large object
 	fmt.Println("\nReading large object...")
+	downloadProgress := progress.StartBytes("Large Object Download", objectSize)
 	startTime = time.Now()
 	resp, err := client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(key),
 	})
 	if err != nil {
+		downloadProgress.Finish()
 		fmt.Printf("Failed to get object: %v\n", err)
 		return
 	}
 
 	// Read all data to fully complete the operation
-	retrievedData, err := io.ReadAll(resp.Body)
+	retrievedData, err := io.ReadAll(downloadProgress.Reader(resp.Body))
 	resp.Body.Close()
 	downloadLatency := time.Since(startTime)
+	downloadProgress.Observe(downloadLatency, int64(len(retrievedData)), err)
+	downloadProgress.Finish()
 	if err != nil {
 		fmt.Printf("Failed to read object data: %v\n", err)
 		return
@@ -197,31 +207,38 @@ func largeObjectTest() {
 		fmt.Printf("Failed to delete object: %v\n", err)
 		return
 	}
+	object.Forget()
 	calculateMetrics([]time.Duration{deleteLatency}, "Large Object Deletion", int(objectSize))
 }
 
 // listOperationsTest tests bucket and object listing operations
-func listOperationsTest() {
+func listOperationsTest(ctx context.Context, tracker *cleanup.Tracker) {
 	fmt.Println("\n===== LIST OPERATIONS TEST =====")
 
 	// Initialize client
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("us-east-1"))
 	if err != nil {
 		fmt.Printf("Failed to load AWS config: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
 	client := s3.NewFromConfig(cfg)
 
-	ctx := context.Background()
-
 	// Part 1: Bucket List Test
 	baseBucketName := fmt.Sprintf("list-test-%d", time.Now().UnixNano())
 	numBuckets := 100
 	var bucketNames []string
+	buckets := make([]*cleanup.Resource, numBuckets)
+	defer func() {
+		// Remove any bucket the timed deletion below did not
+		for _, bucket := range buckets {
+			bucket.Remove(ctx)
+		}
+	}()
 
 	// Create 100 buckets
 	fmt.Printf("\nCreating %d buckets...\n", numBuckets)
 	bucketCreateLatencies := make([]time.Duration, numBuckets)
+	bucketCreateProgress := progress.Start("Bucket Creation", numBuckets)
 
 	for i := 0; i < numBuckets; i++ {
 		bucketName := fmt.Sprintf("%s-%d", baseBucketName, i)
@@ -232,23 +249,28 @@ func listOperationsTest() {
 			Bucket: aws.String(bucketName),
 		})
 		bucketCreateLatencies[i] = time.Since(startTime)
+		bucketCreateProgress.Observe(bucketCreateLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to create bucket %s: %v\n", bucketName, err)
 			continue
 		}
+		buckets[i] = tracker.Track(cleanup.Bucket, bucketName, cleanup.S3Bucket(client, bucketName))
 	}
 
+	bucketCreateProgress.Finish()
 	calculateMetrics(bucketCreateLatencies, "Bucket Creation", 0)
 
 	// List all buckets
 	fmt.Printf("\nListing all buckets...\n")
 	listBucketLatencies := make([]time.Duration, 10) // Perform 10 times for reliable metrics
+	listBucketProgress := progress.Start("Bucket Listing", 10)
 
 	for i := 0; i < 10; i++ {
 		startTime := time.Now()
 		_, err := client.ListBuckets(ctx, &s3.ListBucketsInput{})
 		listBucketLatencies[i] = time.Since(startTime)
+		listBucketProgress.Observe(listBucketLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to list buckets: %v\n", err)
@@ -256,11 +278,13 @@ func listOperationsTest() {
 		}
 	}
 
+	listBucketProgress.Finish()
 	calculateMetrics(listBucketLatencies, "Bucket Listing", 0)
 
 	// Delete all buckets
 	fmt.Printf("\nDeleting %d buckets...\n", numBuckets)
 	bucketDeleteLatencies := make([]time.Duration, len(bucketNames))
+	bucketDeleteProgress := progress.Start("Bucket Deletion", len(bucketNames))
 
 	for i, bucketName := range bucketNames {
 		startTime := time.Now()
@@ -268,13 +292,16 @@ func listOperationsTest() {
 			Bucket: aws.String(bucketName),
 		})
 		bucketDeleteLatencies[i] = time.Since(startTime)
+		bucketDeleteProgress.Observe(bucketDeleteLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to delete bucket %s: %v\n", bucketName, err)
 			continue
 		}
+		buckets[i].Forget()
 	}
 
+	bucketDeleteProgress.Finish()
 	calculateMetrics(bucketDeleteLatencies, "Bucket Deletion", 0)
 
 	// Part 2: Object List Test
@@ -291,20 +318,19 @@ func listOperationsTest() {
 		return
 	}
 
+	objectBucket := tracker.Track(cleanup.Bucket, objectTestBucket, cleanup.S3Bucket(client, objectTestBucket))
 	defer func() {
-		// Clean up bucket at the end
+		// Clean up bucket at the end, with any objects left behind
 		fmt.Printf("\nCleaning up bucket: %s\n", objectTestBucket)
-		_, err = client.DeleteBucket(ctx, &s3.DeleteBucketInput{
-			Bucket: aws.String(objectTestBucket),
-		})
-		if err != nil {
-			fmt.Printf("Failed to delete bucket: %v\n", err)
+		if err := objectBucket.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
 		}
 	}()
 
 	// Create 1000 small objects
 	fmt.Printf("\nCreating %d objects of size 1 byte...\n", numObjects)
 	objectCreateLatencies := make([]time.Duration, numObjects)
+	objectCreateProgress := progress.Start("Object Creation", numObjects)
 	data := []byte("0") // 1 byte of data
 
 	for i := 0; i < numObjects; i++ {
@@ -317,6 +343,7 @@ func listOperationsTest() {
 			Body:   bytes.NewReader(data),
 		})
 		objectCreateLatencies[i] = time.Since(startTime)
+		objectCreateProgress.Observe(objectCreateLatencies[i], 1, err)
 
 		if err != nil {
 			fmt.Printf("Failed to put object: %v\n", err)
@@ -324,11 +351,13 @@ func listOperationsTest() {
 		}
 	}
 
+	objectCreateProgress.Finish()
 	calculateMetrics(objectCreateLatencies, "Object Creation", 1)
 
 	// List all objects
 	fmt.Printf("\nListing all objects...\n")
 	listObjectLatencies := make([]time.Duration, 10) // Perform 10 times
+	listObjectProgress := progress.Start("Object Listing", 10)
 
 	for i := 0; i < 10; i++ {
 		startTime := time.Now()
@@ -336,6 +365,7 @@ func listOperationsTest() {
 			Bucket: aws.String(objectTestBucket),
 		})
 		listObjectLatencies[i] = time.Since(startTime)
+		listObjectProgress.Observe(listObjectLatencies[i], 0, err)
 
 		if err != nil {
 			fmt.Printf("Failed to list objects: %v\n", err)
@@ -343,11 +373,13 @@ func listOperationsTest() {
 		}
 	}
 
+	listObjectProgress.Finish()
 	calculateMetrics(listObjectLatencies, "Object Listing", 0)
 
 	// Delete all objects
 	fmt.Printf("\nDeleting %d objects...\n", numObjects)
 	objectDeleteLatencies := make([]time.Duration, numObjects)
+	objectDeleteProgress := progress.Start("Object Deletion", numObjects)
 
 	for i := 0; i < numObjects; i++ {
 		key := fmt.Sprintf("small-object-%d", i)
@@ -358,6 +390,7 @@ func listOperationsTest() {
 			Key:    aws.String(key),
 		})
 		objectDeleteLatencies[i] = time.Since(startTime)
+		objectDeleteProgress.Observe(objectDeleteLatencies[i], 1, err)
 
 		if err != nil {
 			fmt.Printf("Failed to delete object: %v\n", err)
@@ -365,6 +398,7 @@ func listOperationsTest() {
 		}
 	}
 
+	objectDeleteProgress.Finish()
 	calculateMetrics(objectDeleteLatencies, "Object Deletion", 1)
 }
 
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/Accelerated-Cloud-Storage/Benchmarks/bench/cleanup"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -29,9 +30,12 @@ func main() {
 		o.UsePathStyle = false
 	})
 
+	// Remove everything the test creates, even on Ctrl-C or a panic
+	tracker, ctx := cleanup.New(context.Background(), os.Stdout)
+	defer tracker.Close()
+
 	// Create a unique bucket for testing
 	bucket := fmt.Sprintf("test-bucket-%d", time.Now().UnixNano())
-	ctx := context.Background()
 
 	// Create bucket
 	fmt.Printf("Creating bucket: %s\n", bucket)
@@ -40,34 +44,14 @@ func main() {
 	})
 	if err != nil {
 		fmt.Printf("Failed to create bucket: %v\n", err)
-		os.Exit(1)
+		tracker.Exit(1)
 	}
+	bucketResource := tracker.Track(cleanup.Bucket, bucket, cleanup.S3Bucket(client, bucket))
 	defer func() {
 		fmt.Printf("Cleaning up bucket: %s\n", bucket)
-		// Delete all objects before deleting bucket
-		listObjectsResp, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-			Bucket: aws.String(bucket),
-		})
-		if err != nil {
-			fmt.Printf("Failed to list objects: %v\n", err)
-			return
-		}
-
-		for _, obj := range listObjectsResp.Contents {
-			_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    obj.Key,
-			})
-			if err != nil {
-				fmt.Printf("Failed to delete object %s: %v\n", *obj.Key, err)
-			}
-		}
-
-		_, err = client.DeleteBucket(ctx, &s3.DeleteBucketInput{
-			Bucket: aws.String(bucket),
-		})
-		if err != nil {
-			fmt.Printf("Failed to delete bucket: %v\n", err)
+		// Delete all objects, on every page of the listing, before deleting bucket
+		if err := bucketResource.Remove(ctx); err != nil {
+			fmt.Printf("Failed to clean up bucket: %v\n", err)
 		}
 	}()
 	